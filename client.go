@@ -12,21 +12,32 @@ import (
 
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/handler/extension"
-	"github.com/99designs/gqlgen/graphql/handler/lru"
 	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
 	"github.com/vektah/gqlparser/v2/ast"
 	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/graph"
-	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/database"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/apikey"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/auth"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/certs"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/events"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/grpcclient"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/health"
 	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/helpers"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/limits"
 	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/pkg"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/querycache"
 	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/queue"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/repository"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/requestid"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/safelist"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/tenant"
 	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/pb"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 func main() {
@@ -46,8 +57,10 @@ func main() {
 		os.Exit(1)
 	}
 
-	address := fmt.Sprintf(":%d", cfg.GrpcServer.Port)
-	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpcclient.Dial(cfg.ProductService, cfg.GrpcServer.Port,
+		grpc.WithChainUnaryInterceptor(requestid.UnaryClientInterceptor(), tenant.UnaryClientInterceptor(), auth.UnaryClientInterceptor(), apikey.UnaryClientInterceptor()),
+		grpc.WithChainStreamInterceptor(requestid.StreamClientInterceptor(), auth.StreamClientInterceptor()),
+	)
 	if err != nil {
 		slog.Error("failed to create grpc client", "error", err)
 		os.Exit(1)
@@ -60,19 +73,17 @@ func main() {
 		slog.Error("failed to load .env file", "error", err)
 		os.Exit(1)
 	}
-	astraCfg := &database.AstraConfig{
-		Username: cfg.Database.Username,
-		Path:     cfg.Database.Path,
-		Token:    helpers.GetEnvOrDefault("DATABASE_TOKEN", ""),
-	}
-
-	db := database.NewAstraDB()
-	session, err := db.Connect(ctx, astraCfg, 30*time.Second)
+	repo, err := repository.Open(ctx, cfg.Database, 30*time.Second)
 	if err != nil {
 		slog.Error("failed to connect to database", "error", err)
 		os.Exit(1)
 	}
-	defer session.Close()
+	defer repo.Close()
+
+	if err := repo.Prepare(ctx); err != nil {
+		slog.Error("failed to prepare database statements", "error", err)
+		os.Exit(1)
+	}
 
 	pulsarCfg := &queue.PulsarConfig{
 		URI:       cfg.Queue.URI,
@@ -98,28 +109,111 @@ func main() {
 
 	client := pb.NewProductServiceClient(conn)
 
-	srv := handler.New(graph.NewExecutableSchema(graph.Config{Resolvers: &graph.Resolver{Conn: client}}))
+	broker := events.NewBroker()
+
+	verifier, err := auth.Open(ctx, cfg.Auth)
+	if err != nil {
+		slog.Error("failed to load the JWKS", "error", err)
+		os.Exit(1)
+	}
+	if verifier == nil {
+		slog.Warn("authentication is disabled, set auth.jwks to require tokens")
+	}
+
+	limitsCfg := cfg.GraphqlServer.Limits
+	complexity, err := graph.NewComplexity(limitsCfg.Weights)
+	if err != nil {
+		slog.Error("invalid complexity weights", "error", err)
+		os.Exit(1)
+	}
+
+	srv := handler.New(graph.NewExecutableSchema(graph.Config{
+		Resolvers:  &graph.Resolver{Conn: client, Events: broker},
+		Complexity: complexity,
+		Directives: graph.DirectiveRoot{HasRole: graph.HasRole(verifier != nil)},
+	}))
 
 	srv.AddTransport(transport.Options{})
 	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.SSE{}) // must be added before POST
 	srv.AddTransport(transport.POST{})
+	websocket := transport.Websocket{
+		KeepAlivePingInterval: 10 * time.Second,
+	}
+	if verifier != nil {
+		websocket.InitFunc = verifier.WebsocketInit
+	}
+	srv.AddTransport(websocket)
 
-	srv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
+	srv.SetErrorPresenter(graph.ErrorPresenter(cfg.GraphqlServer.Production()))
+	srv.SetQueryCache(querycache.NewLocal[*ast.QueryDocument]("query", 1000))
 
+	srv.Use(graph.NewMetrics())
+	srv.Use(graph.NewLoaderExtension(client))
 	srv.Use(extension.Introspection{})
+	if cfg.GraphqlServer.Safelist != "" {
+		operations, err := safelist.New(cfg.GraphqlServer.Safelist, cfg.GraphqlServer.Production())
+		if err != nil {
+			slog.Error("failed to load safelist", "error", err)
+			os.Exit(1)
+		}
+		go func() {
+			if err := operations.Watch(context.Background()); err != nil {
+				slog.Error("failed to watch safelist", "error", err)
+			}
+		}()
+		srv.Use(operations) // before APQ, which then finds the safelisted query
+	}
+	cacheStore, err := sharedStore(cfg.GraphqlServer.Cache, repo)
+	if err != nil {
+		slog.Error("failed to create the shared query cache", "error", err)
+		os.Exit(1)
+	}
 	srv.Use(extension.AutomaticPersistedQuery{
-		Cache: lru.New[string](100),
+		Cache: querycache.New("apq", 1000, cacheStore),
 	})
+	if limitsCfg.MaxDepth > 0 {
+		srv.Use(limits.MaxDepth{Limit: limitsCfg.MaxDepth})
+	}
+	if limitsCfg.MaxComplexity > 0 {
+		srv.Use(extension.FixedComplexityLimit(limitsCfg.MaxComplexity))
+	}
+	budget := limits.NewBudget(limitsCfg.Budget.PerMinute, limitsCfg.Budget.Keys)
+	if limitsCfg.Budget.PerMinute > 0 {
+		srv.Use(budget)
+	}
+
+	readiness := health.NewReadiness(conn, grpcclient.ServiceName, 2*time.Second)
 
 	mux := chi.NewRouter()
-	mux.Use(middleware.Logger)
+	// Probes and scrapes skip the access log and the tenant, authentication and rate limiting middlewares.
+	mux.Get("/healthz", health.Live)
+	mux.Handle("/readyz", readiness)
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.Group(func(mux chi.Router) {
+		mux.Use(middleware.RequestID)
+		mux.Use(requestid.Middleware)
+		mux.Use(middleware.Logger)
+		mux.Use(tenant.Middleware)
+		if verifier != nil {
+			mux.Use(verifier.Middleware)
+		}
+		mux.Use(apikey.NewLimiter(repo, cfg.APIKeys.CacheTTL, 1).Middleware)
+		mux.Use(budget.Middleware)
 
-	mux.Handle("/", playground.Handler("GraphQL playground", "/query"))
-	mux.Handle("/query", srv)
+		mux.Handle("/", playground.Handler("GraphQL playground", "/query"))
+		mux.Handle("/query", srv)
+	})
 
+	serverTLS, err := certs.ServerConfig(context.Background(), cfg.GraphqlServer.TLS)
+	if err != nil {
+		slog.Error("failed to load GraphQL server TLS configuration", "error", err)
+		os.Exit(1)
+	}
 	server := &http.Server{
-		Addr:    fmt.Sprintf(":%s", cfg.GraphqlServer.Port),
-		Handler: mux,
+		Addr:      fmt.Sprintf(":%s", cfg.GraphqlServer.Port),
+		Handler:   mux,
+		TLSConfig: serverTLS,
 	}
 
 	stopCH := make(chan os.Signal, 1)
@@ -128,17 +222,23 @@ func main() {
 	defer shutdownCancel()
 
 	go func() {
-		slog.Info("SERVER starting", "port", cfg.GraphqlServer.Port)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		slog.Info("SERVER starting", "port", cfg.GraphqlServer.Port, "tls", serverTLS != nil)
+		listen := server.ListenAndServe
+		if serverTLS != nil {
+			// The certificate comes from TLSConfig, which reloads it.
+			listen = func() error { return server.ListenAndServeTLS("", "") }
+		}
+		if err := listen(); err != nil && err != http.ErrServerClosed {
 			slog.Error("failed to start server", "error", err)
 			os.Exit(1)
 		}
 	}()
 
-	go helpers.ConsumeMessages(context.Background(), consumer, session)
+	go helpers.ConsumeMessages(context.Background(), consumer, repo, broker)
 
 	<-stopCH
 	slog.Info("shutting down the server...")
+	readiness.Stop()
 	if err := server.Shutdown(shutdownCtx); err != nil {
 		slog.Error("failed to shutdown server", "error", err)
 		os.Exit(1)
@@ -147,3 +247,31 @@ func main() {
 	}
 
 }
+
+// sharedStore returns the store persisted queries are shared through, nil keeping them in memory.
+func sharedStore(cfg pkg.GraphqlCache, repo repository.Repository) (querycache.Store, error) {
+	ttl := cfg.TTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+
+	switch cfg.Store {
+	case "", "memory":
+		return nil, nil
+	case "cassandra":
+		cassandra, ok := repo.(*repository.CassandraRepository)
+		if !ok {
+			return nil, fmt.Errorf("the cassandra query cache needs a CQL database, not %T", repo)
+		}
+		return querycache.NewCassandraStore(cassandra.Session(), cassandra.KeyspaceNames()[0], ttl), nil
+	case "redis":
+		client := redis.NewClient(&redis.Options{
+			Addr:     cfg.Redis.Address,
+			Password: helpers.GetEnvOrDefault("REDIS_PASSWORD", ""),
+			DB:       cfg.Redis.DB,
+		})
+		return querycache.NewRedisStore(client, cfg.Redis.Prefix, ttl), nil
+	default:
+		return nil, fmt.Errorf("unknown query cache store %q", cfg.Store)
+	}
+}
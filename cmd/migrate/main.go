@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/migrate"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/pkg"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/repository"
+)
+
+// schema migration runner
+//
+//	go run ./cmd/migrate [-config config.yaml] [-timeout 5m] up|status
+
+func main() {
+	configPath := flag.String("config", "config.yaml", "path to the configuration file")
+	timeout := flag.Duration("timeout", 5*time.Minute, "overall timeout, including waiting for the migration lock")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] up|status\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 || (flag.Arg(0) != "up" && flag.Arg(0) != "status") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var cfg pkg.Config
+	file, err := os.Open(*configPath)
+	if err != nil {
+		slog.Error("failed to open config", "error", err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	if err := cfg.LoadConfig(file); err != nil {
+		slog.Error("failed to load config", "error", err)
+		os.Exit(1)
+	}
+
+	if err := godotenv.Load(); err != nil {
+		slog.Warn("failed to load .env file", "error", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	repo, err := repository.Open(ctx, cfg.Database, 30*time.Second)
+	if err != nil {
+		slog.Error("failed to connect to database", "error", err)
+		os.Exit(1)
+	}
+	defer repo.Close()
+
+	migrators, err := migrate.ForRepository(repo)
+	if err != nil {
+		slog.Error("failed to load migrations", "error", err)
+		os.Exit(1)
+	}
+
+	switch flag.Arg(0) {
+	case "up":
+		err = up(ctx, migrators)
+	case "status":
+		err = status(ctx, migrators)
+	}
+	if err != nil {
+		slog.Error("migration command failed", "command", flag.Arg(0), "error", err)
+		os.Exit(1)
+	}
+}
+
+func up(ctx context.Context, migrators []*migrate.Migrator) error {
+	for _, m := range migrators {
+		applied, err := m.Up(ctx)
+		if err != nil {
+			return fmt.Errorf("%s: %w", m.Target(), err)
+		}
+		slog.Info("migrations applied", "target", m.Target(), "count", len(applied))
+	}
+	return nil
+}
+
+func status(ctx context.Context, migrators []*migrate.Migrator) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "TARGET\tVERSION\tNAME\tSTATUS")
+	for _, m := range migrators {
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return fmt.Errorf("%s: %w", m.Target(), err)
+		}
+		for _, s := range statuses {
+			state := "pending"
+			switch {
+			case s.Modified():
+				state = "modified since applied at " + s.Applied.AppliedAt.Format(time.RFC3339)
+			case !s.Pending():
+				state = "applied at " + s.Applied.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%s\t%04d\t%s\t%s\n", m.Target(), s.Migration.Version, s.Migration.Name, state)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/formatter"
+	"github.com/vektah/gqlparser/v2/parser"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/safelist"
+)
+
+// persisted query manifest generator
+//
+//	go run ./cmd/safelist [-schema graph/schema.graphqls] [-o persisted-queries.json] dir...
+//
+// Operations are read from .graphql and .gql files and from gql`...` and graphql`...`
+// templates in JavaScript and TypeScript files. Clients send the operations as written
+// in the manifest, or only their hash.
+
+var (
+	templateLiteral = regexp.MustCompile("(?s)(?:gql|graphql)\\s*`([^`]*)`")
+	interpolation   = regexp.MustCompile(`\$\{[^}]*\}`)
+)
+
+var scriptExtensions = map[string]bool{".js": true, ".jsx": true, ".ts": true, ".tsx": true, ".mjs": true}
+
+func main() {
+	schemaPath := flag.String("schema", "graph/schema.graphqls", "schema the operations are validated against")
+	output := flag.String("o", "persisted-queries.json", "manifest to write, - for stdout")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] dir...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	manifest, err := extract(*schemaPath, flag.Args())
+	if err != nil {
+		slog.Error("failed to extract operations", "error", err)
+		os.Exit(1)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		slog.Error("failed to encode manifest", "error", err)
+		os.Exit(1)
+	}
+	data = append(data, '\n')
+
+	if *output == "-" {
+		_, err = os.Stdout.Write(data)
+	} else {
+		err = os.WriteFile(*output, data, 0o644)
+	}
+	if err != nil {
+		slog.Error("failed to write manifest", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("wrote manifest", "path", *output, "operations", len(manifest.Operations))
+}
+
+// extract collects the operations and fragments defined under dirs and returns a manifest
+// with one entry per operation, including the fragments it uses.
+func extract(schemaPath string, dirs []string) (*safelist.Manifest, error) {
+	schemaSource, err := os.ReadFile(schemaPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+	schema, err := gqlparser.LoadSchema(&ast.Source{Name: schemaPath, Input: string(schemaSource)})
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	var operations ast.OperationList
+	fragments := make(map[string]*ast.FragmentDefinition)
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if d.Name() == "node_modules" || (strings.HasPrefix(d.Name(), ".") && path != dir) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			documents, err := readDocuments(path)
+			if err != nil {
+				return err
+			}
+			for _, document := range documents {
+				doc, err := parser.ParseQuery(&ast.Source{Name: path, Input: document})
+				if err != nil {
+					return fmt.Errorf("%s: %w", path, err)
+				}
+				operations = append(operations, doc.Operations...)
+				for _, fragment := range doc.Fragments {
+					if _, ok := fragments[fragment.Name]; ok {
+						return fmt.Errorf("%s: fragment %q is defined twice", path, fragment.Name)
+					}
+					fragments[fragment.Name] = fragment
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	manifest := &safelist.Manifest{Format: safelist.ManifestFormat, Version: 1, Operations: []safelist.Operation{}}
+	names := make(map[string]bool)
+	for _, op := range operations {
+		if op.Name == "" {
+			return nil, fmt.Errorf("%s: operations must be named", op.Position.Src.Name)
+		}
+		if names[op.Name] {
+			return nil, fmt.Errorf("%s: operation %q is defined twice", op.Position.Src.Name, op.Name)
+		}
+		names[op.Name] = true
+
+		doc := &ast.QueryDocument{Operations: ast.OperationList{op}}
+		if err := addFragments(doc, op.SelectionSet, fragments, make(map[string]bool)); err != nil {
+			return nil, fmt.Errorf("%s: operation %q: %w", op.Position.Src.Name, op.Name, err)
+		}
+
+		var body bytes.Buffer
+		formatter.NewFormatter(&body).FormatQueryDocument(doc)
+
+		// Validate the printed operation so positions in errors refer to the manifest body.
+		if _, errs := gqlparser.LoadQuery(schema, body.String()); len(errs) > 0 {
+			return nil, fmt.Errorf("%s: operation %q: %w", op.Position.Src.Name, op.Name, errs)
+		}
+
+		manifest.Operations = append(manifest.Operations, safelist.Operation{
+			ID:   safelist.Hash(body.String()),
+			Name: op.Name,
+			Type: string(op.Operation),
+			Body: body.String(),
+		})
+	}
+
+	sort.Slice(manifest.Operations, func(i, j int) bool { return manifest.Operations[i].Name < manifest.Operations[j].Name })
+	return manifest, nil
+}
+
+// readDocuments returns the GraphQL documents in a file.
+func readDocuments(path string) ([]string, error) {
+	ext := filepath.Ext(path)
+	if ext != ".graphql" && ext != ".gql" && !scriptExtensions[ext] {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !scriptExtensions[ext] {
+		return []string{string(data)}, nil
+	}
+
+	// Interpolations only embed fragments, which are collected from where they are defined.
+	var documents []string
+	for _, match := range templateLiteral.FindAllStringSubmatch(string(data), -1) {
+		documents = append(documents, interpolation.ReplaceAllString(match[1], ""))
+	}
+	return documents, nil
+}
+
+// addFragments adds the fragments spread in set, and those they use, to doc.
+func addFragments(doc *ast.QueryDocument, set ast.SelectionSet, fragments map[string]*ast.FragmentDefinition, added map[string]bool) error {
+	for _, selection := range set {
+		switch s := selection.(type) {
+		case *ast.Field:
+			if err := addFragments(doc, s.SelectionSet, fragments, added); err != nil {
+				return err
+			}
+		case *ast.InlineFragment:
+			if err := addFragments(doc, s.SelectionSet, fragments, added); err != nil {
+				return err
+			}
+		case *ast.FragmentSpread:
+			if added[s.Name] {
+				continue
+			}
+			fragment, ok := fragments[s.Name]
+			if !ok {
+				return fmt.Errorf("unknown fragment %q", s.Name)
+			}
+			added[s.Name] = true
+			doc.Fragments = append(doc.Fragments, fragment)
+			if err := addFragments(doc, fragment.SelectionSet, fragments, added); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -5,20 +5,36 @@ import (
 	"fmt"
 	"log/slog"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/apikey"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/auth"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/certs"
 	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/controller"
-	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/database"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/cursor"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/events"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/health"
 	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/helpers"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/interceptor"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/migrate"
 	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/pkg"
 	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/queue"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/repository"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/requestid"
 	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/snowflake"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/tenant"
 	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/pb"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/reflection"
 )
 
@@ -53,19 +69,29 @@ func main() {
 		os.Exit(1)
 
 	}
-	astraCfg := &database.AstraConfig{
-		Username: cfg.Database.Username,
-		Path:     cfg.Database.Path,
-		Token:    helpers.GetEnvOrDefault("DATABASE_TOKEN", ""),
-	}
-
-	db := database.NewAstraDB()
-	session, err := db.Connect(ctx, astraCfg, 30*time.Second)
+	repo, err := repository.Open(ctx, cfg.Database, 30*time.Second)
 	if err != nil {
 		slog.Error("failed to connect to database", "error", err)
 		os.Exit(1)
 	}
-	defer session.Close()
+	defer repo.Close()
+
+	if cfg.Database.Migrations.RequireCurrent {
+		migrators, err := migrate.ForRepository(repo)
+		if err != nil {
+			slog.Error("failed to load migrations", "error", err)
+			os.Exit(1)
+		}
+		if err := migrate.CheckCurrent(ctx, migrators); err != nil {
+			slog.Error("database schema is not up to date", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	if err := repo.Prepare(ctx); err != nil {
+		slog.Error("failed to prepare database statements", "error", err)
+		os.Exit(1)
+	}
 	pulsarCfg := &queue.PulsarConfig{
 		URI:       cfg.Queue.URI,
 		TopicName: cfg.Queue.Topic,
@@ -93,11 +119,134 @@ func main() {
 		os.Exit(1)
 	}
 
-	productContoller := controller.NewProductController(session)
+	cursorTTL := cfg.GrpcServer.CursorTTL
+	if cursorTTL <= 0 {
+		cursorTTL = time.Hour
+	}
+	var cursors *cursor.Codec
+	if secret := helpers.GetEnvOrDefault("CURSOR_SECRET", ""); secret != "" {
+		cursors = cursor.NewCodec([]byte(secret), cursorTTL)
+	} else {
+		slog.Warn("CURSOR_SECRET is not set, paging cursors are only valid on this instance until it restarts")
+		if cursors, err = cursor.NewRandomCodec(cursorTTL); err != nil {
+			slog.Error("failed to create cursor key", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	productContoller := controller.NewProductController(repo, events.NewPulsarWatcher(queueInstance, client), cursors)
+
+	knownTenant := func(tenantID string) bool {
+		_, ok := cfg.Database.TenantKeyspaces[tenantID]
+		return ok
+	}
+
+	keyLimiter := apikey.NewLimiter(repo, cfg.APIKeys.CacheTTL, cfg.APIKeys.RPCsPerRequest)
+	// Request ids come first so that access logs and recovered panics carry them; recovery runs
+	// inside logging so that panics are logged with the Internal code they end with.
+	unaryInterceptors := []grpc.UnaryServerInterceptor{
+		requestid.UnaryServerInterceptor(),
+		interceptor.UnaryLogging(),
+		interceptor.UnaryMetrics(),
+		interceptor.UnaryRecovery(),
+		tenant.UnaryServerInterceptor(knownTenant),
+		keyLimiter.UnaryServerInterceptor(),
+	}
+	streamInterceptors := []grpc.StreamServerInterceptor{
+		requestid.StreamServerInterceptor(),
+		interceptor.StreamLogging(),
+		interceptor.StreamMetrics(),
+		interceptor.StreamRecovery(),
+		tenant.StreamServerInterceptor(knownTenant),
+		keyLimiter.StreamServerInterceptor(),
+	}
 
-	server := grpc.NewServer()
+	verifier, err := auth.Open(ctx, cfg.Auth)
+	if err != nil {
+		slog.Error("failed to load the JWKS", "error", err)
+		os.Exit(1)
+	}
+	if verifier != nil {
+		// Roles required by the writes, matching @hasRole in the GraphQL schema.
+		roles := map[string]string{
+			pb.ProductService_CreateCategory_FullMethodName: auth.RoleAdmin,
+			pb.ProductService_CreateProduct_FullMethodName:  auth.RoleEditor,
+			pb.ProductService_UpdateProduct_FullMethodName:  auth.RoleEditor,
+			pb.AdminService_IssueAPIKey_FullMethodName:      auth.RoleAdmin,
+			pb.AdminService_RevokeAPIKey_FullMethodName:     auth.RoleAdmin,
+		}
+		unaryInterceptors = append(unaryInterceptors, verifier.UnaryServerInterceptor(roles))
+		streamInterceptors = append(streamInterceptors, verifier.StreamServerInterceptor(roles))
+	} else {
+		slog.Warn("authentication is disabled, set auth.jwks to require tokens")
+	}
+
+	// The default policy closes connections pinged more often than every 5 minutes.
+	keepaliveMinTime := cfg.GrpcServer.KeepaliveMinTime
+	if keepaliveMinTime <= 0 {
+		keepaliveMinTime = 10 * time.Second
+	}
+	serverOptions := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             keepaliveMinTime,
+			PermitWithoutStream: true,
+		}),
+	}
+	serverTLS, err := certs.ServerConfig(context.Background(), cfg.GrpcServer.TLS)
+	if err != nil {
+		slog.Error("failed to load grpc server TLS configuration", "error", err)
+		os.Exit(1)
+	}
+	if serverTLS != nil {
+		serverOptions = append(serverOptions, grpc.Creds(credentials.NewTLS(serverTLS)))
+	} else {
+		slog.Warn("the grpc server accepts plaintext connections, set grpc_server.tls to enable TLS")
+	}
+
+	server := grpc.NewServer(serverOptions...)
 	reflection.Register(server) //use server reflection, not required
 	pb.RegisterProductServiceServer(server, productContoller)
+	if verifier != nil {
+		pb.RegisterAdminServiceServer(server, controller.NewAdminController(repo, controller.KeyDefaults{
+			RequestsPerSecond: cfg.APIKeys.RequestsPerSecond,
+			Burst:             cfg.APIKeys.Burst,
+		}))
+	} else {
+		slog.Warn("the admin service is not registered while authentication is disabled")
+	}
+
+	healthServer := grpchealth.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	healthInterval := cfg.GrpcServer.HealthInterval
+	if healthInterval <= 0 {
+		healthInterval = 10 * time.Second
+	}
+	monitor := health.NewMonitor(healthServer, min(healthInterval, 5*time.Second))
+	// WatchProducts reads the event topic and the outbox relay publishes to it, so the product
+	// service needs Pulsar as well as the database.
+	monitor.Add("database", repo.Ping, pb.ProductService_ServiceDesc.ServiceName, pb.AdminService_ServiceDesc.ServiceName)
+	monitor.Add("pulsar", func(ctx context.Context) error {
+		return queue.PingProducer(ctx, client, producer)
+	}, pb.ProductService_ServiceDesc.ServiceName)
+	healthCtx, stopHealth := context.WithCancel(context.Background())
+	defer stopHealth()
+	go monitor.Run(healthCtx, healthInterval)
+
+	var metricsServer *http.Server
+	if cfg.GrpcServer.MetricsPort > 0 {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", promhttp.Handler())
+		metricsServer = &http.Server{Addr: fmt.Sprintf(":%d", cfg.GrpcServer.MetricsPort), Handler: metricsMux}
+		go func() {
+			slog.Info("Starting metrics server", "port", cfg.GrpcServer.MetricsPort)
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				slog.Error("metrics server encountered an error while serving", "error", err)
+			}
+		}()
+	}
+
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 	stopCH := make(chan os.Signal, 1)
@@ -112,7 +261,7 @@ func main() {
 			select {
 			case <-ticker.C:
 				// poll messages
-				if err := helpers.ProcessMessages(context.Background(), session, producer); err != nil {
+				if err := helpers.ProcessMessages(context.Background(), repo, producer); err != nil {
 					slog.Error("failed to process messages", "error", err)
 					os.Exit(1)
 				}
@@ -127,8 +276,14 @@ func main() {
 		slog.Info("Received shutdown signal", "signal", sig)
 		slog.Info("Shutting down gRPC server...")
 
-		// Gracefully stop the gRPC server
+		// Report NOT_SERVING so that clients move to other instances, then stop gracefully
+		stopHealth()
+		healthServer.Shutdown()
+		time.Sleep(cfg.GrpcServer.DrainDelay)
 		server.GracefulStop()
+		if metricsServer != nil {
+			metricsServer.Close()
+		}
 		cancel()      // Cancel context for other goroutines
 		close(stopCH) // Notify the polling goroutine to stop
 
@@ -0,0 +1,98 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/graph/model"
+)
+
+// DefaultWeights are the costs of the fields that call the product service, by Type.field.
+// Every other field costs 1.
+var DefaultWeights = map[string]int{
+	"Query.getCategory":            5,
+	"Query.getProduct":             5,
+	"Query.listProducts":           10,
+	"Query.productsConnection":     10,
+	"Query._entities":              5, // plus its selection once per representation
+	"Category.products":            10,
+	"Product.category":             2, // batched by the category loader
+	"ProductConnection.totalCount": 10,
+	"Mutation.createCategory":      20,
+	"Mutation.createProduct":       20,
+	"Mutation.updateProduct":       20,
+}
+
+// NewComplexity returns the complexity functions of the schema: a weighted field costs its
+// weight, plus its selection once per item for the paged ones. weights overrides DefaultWeights.
+func NewComplexity(weights map[string]int) (ComplexityRoot, error) {
+	w := make(map[string]int, len(DefaultWeights))
+	for field, weight := range DefaultWeights {
+		w[field] = weight
+	}
+	for field, weight := range weights {
+		if _, ok := DefaultWeights[field]; !ok {
+			return ComplexityRoot{}, fmt.Errorf("unknown complexity weight %q, expected one of %s", field, weightNames())
+		}
+		if weight < 0 {
+			return ComplexityRoot{}, fmt.Errorf("complexity weight %q must not be negative", field)
+		}
+		w[field] = weight
+	}
+
+	var c ComplexityRoot
+	c.Query.GetCategory = func(childComplexity int, id int64) int {
+		return w["Query.getCategory"] + childComplexity
+	}
+	c.Query.GetProduct = func(childComplexity int, categoryID int64, productID int64) int {
+		return w["Query.getProduct"] + childComplexity
+	}
+	c.Query.ListProducts = func(childComplexity int, categoryID int64, pageSize *int32, pagingState *string) int {
+		return w["Query.listProducts"] + pageSizeOf(pageSize)*childComplexity
+	}
+	c.Query.ProductsConnection = func(childComplexity int, categoryID int64, first *int32, after *string) int {
+		return w["Query.productsConnection"] + pageSizeOf(first)*childComplexity
+	}
+	c.Query.__resolve_entities = func(childComplexity int, representations []map[string]any) int {
+		return w["Query._entities"] + len(representations)*childComplexity
+	}
+	c.Category.Products = func(childComplexity int, first *int32, after *string) int {
+		return w["Category.products"] + pageSizeOf(first)*childComplexity
+	}
+	c.Product.Category = func(childComplexity int) int {
+		return w["Product.category"] + childComplexity
+	}
+	c.ProductConnection.TotalCount = func(childComplexity int) int {
+		return w["ProductConnection.totalCount"] + childComplexity
+	}
+	c.Mutation.CreateCategory = func(childComplexity int, input model.CreateCategoryInput) int {
+		return w["Mutation.createCategory"] + childComplexity
+	}
+	c.Mutation.CreateProduct = func(childComplexity int, input model.CreateProductInput) int {
+		return w["Mutation.createProduct"] + childComplexity
+	}
+	c.Mutation.UpdateProduct = func(childComplexity int, input model.UpdateProductInput) int {
+		return w["Mutation.updateProduct"] + childComplexity
+	}
+	return c, nil
+}
+
+// pageSizeOf returns the number of items a paged field fetches with the page size argument
+// size. Fields given an invalid size fail without fetching any, they are scored as one.
+func pageSizeOf(size *int32) int {
+	limit, err := pageLimit("", size)
+	if err != nil {
+		return 1
+	}
+	return int(limit)
+}
+
+func weightNames() string {
+	names := make([]string, 0, len(DefaultWeights))
+	for field := range DefaultWeights {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,87 @@
+package graph
+
+import "testing"
+
+func int32p(v int32) *int32 { return &v }
+
+func TestPageSizeOf(t *testing.T) {
+	tests := []struct {
+		name string
+		size *int32
+		want int
+	}{
+		{"default", nil, defaultPageSize},
+		{"within range", int32p(25), 25},
+		{"maximum", int32p(maxPageSize), maxPageSize},
+		{"above maximum", int32p(1_000_000), 1},
+		{"zero", int32p(0), 1},
+		{"negative", int32p(-5), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pageSizeOf(tt.size); got != tt.want {
+				t.Errorf("pageSizeOf() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPageLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    *int32
+		want    int32
+		wantErr bool
+	}{
+		{"default", nil, defaultPageSize, false},
+		{"within range", int32p(1), 1, false},
+		{"maximum", int32p(maxPageSize), maxPageSize, false},
+		{"above maximum", int32p(maxPageSize + 1), 0, true},
+		{"zero", int32p(0), 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pageLimit("pageSize", tt.size)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("pageLimit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("pageLimit() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewComplexityListProducts(t *testing.T) {
+	c, err := NewComplexity(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A page size the resolver rejects must not be scored as a full page, nor one above the limit as more.
+	if got, want := c.Query.ListProducts(3, 1, int32p(1_000_000), nil), DefaultWeights["Query.listProducts"]+3; got != want {
+		t.Errorf("complexity of an oversized page = %d, want %d", got, want)
+	}
+	if got, want := c.Query.ListProducts(3, 1, int32p(50), nil), DefaultWeights["Query.listProducts"]+50*3; got != want {
+		t.Errorf("complexity of a page of 50 = %d, want %d", got, want)
+	}
+}
+
+func TestNewComplexityEntities(t *testing.T) {
+	c, err := NewComplexity(map[string]int{"Query._entities": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	representations := make([]map[string]any, 40)
+	if got, want := c.Query.__resolve_entities(4, representations), 1+40*4; got != want {
+		t.Errorf("complexity of 40 entities = %d, want %d", got, want)
+	}
+}
+
+func TestNewComplexityRejectsInvalidWeights(t *testing.T) {
+	if _, err := NewComplexity(map[string]int{"Query.nope": 1}); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+	if _, err := NewComplexity(map[string]int{"Query.getProduct": -1}); err == nil {
+		t.Error("expected an error for a negative weight")
+	}
+}
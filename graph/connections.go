@@ -0,0 +1,67 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/graph/model"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+)
+
+// pageLimit returns the number of products to request for the page size argument named arg,
+// defaultPageSize when it is not set.
+func pageLimit(arg string, size *int32) (int32, error) {
+	if size == nil {
+		return defaultPageSize, nil
+	}
+	if *size < 1 || *size > maxPageSize {
+		return 0, status.Errorf(codes.InvalidArgument, "%s must be between 1 and %d", arg, maxPageSize)
+	}
+	return *size, nil
+}
+
+// productsConnection fetches the page of a category's products selected by first and after.
+func (r *Resolver) productsConnection(ctx context.Context, categoryID int64, first *int32, after *string) (*model.ProductConnection, error) {
+	limit, err := pageLimit("first", first)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &pb.ListProductsRequest{CategoryId: categoryID, PageSize: limit}
+	if after != nil {
+		req.PageToken = *after
+	}
+
+	resp, err := r.Conn.ListProducts(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch products: %w", err)
+	}
+
+	return productConnection(categoryID, resp), nil
+}
+
+// productConnection builds the connection of a ListProducts page from the signed cursors issued by the product service.
+func productConnection(categoryID int64, resp *pb.ListProductsResponse) *model.ProductConnection {
+	edges := make([]*model.ProductEdge, len(resp.Products))
+	for i, p := range resp.Products {
+		edges[i] = &model.ProductEdge{Node: productModel(p)}
+		if i < len(resp.Cursors) {
+			edges[i].Cursor = resp.Cursors[i]
+		}
+	}
+
+	pageInfo := &model.PageInfo{HasNextPage: resp.NextPageToken != ""}
+	if len(edges) > 0 {
+		pageInfo.StartCursor = &edges[0].Cursor
+		pageInfo.EndCursor = &edges[len(edges)-1].Cursor
+	}
+
+	return &model.ProductConnection{Edges: edges, PageInfo: pageInfo, CategoryID: categoryID}
+}
@@ -0,0 +1,31 @@
+package graph
+
+import (
+	"context"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/graph/model"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// HasRole implements @hasRole with the claims auth.Middleware stored in the context. When
+// authentication is disabled every caller is let through, the product service decides alone.
+func HasRole(enabled bool) func(ctx context.Context, obj any, next graphql.Resolver, role model.Role) (any, error) {
+	return func(ctx context.Context, obj any, next graphql.Resolver, role model.Role) (any, error) {
+		if !enabled {
+			return next(ctx)
+		}
+
+		claims, ok := auth.FromContext(ctx)
+		if !ok {
+			return nil, status.Errorf(codes.Unauthenticated, "%s requires authentication", graphql.GetFieldContext(ctx).Field.Name)
+		}
+		if !claims.HasRole(strings.ToLower(role.String())) {
+			return nil, status.Errorf(codes.PermissionDenied, "%s requires the %s role", graphql.GetFieldContext(ctx).Field.Name, role)
+		}
+		return next(ctx)
+	}
+}
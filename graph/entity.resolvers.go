@@ -0,0 +1,57 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.66
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/graph/model"
+)
+
+// FindManyCategoryByIDs is the resolver for the findManyCategoryByIDs field.
+func (r *entityResolver) FindManyCategoryByIDs(ctx context.Context, reps []*model.CategoryByIDsInput) ([]*model.Category, error) {
+	ids := make([]int64, len(reps))
+	for i, rep := range reps {
+		ids[i] = rep.ID
+	}
+
+	categories, errs := loadersFor(ctx).categories.LoadMany(ctx, ids)()
+	entities := make([]*model.Category, len(reps))
+	for i, category := range categories {
+		if err := entityError(errs, i); err != nil {
+			return nil, fmt.Errorf("failed to resolve category entities: %w", err)
+		}
+		if category != nil {
+			entities[i] = categoryModel(category)
+		}
+	}
+	return entities, nil
+}
+
+// FindManyProductByIDs is the resolver for the findManyProductByIDs field.
+func (r *entityResolver) FindManyProductByIDs(ctx context.Context, reps []*model.ProductByIDsInput) ([]*model.Product, error) {
+	ids := make([]int64, len(reps))
+	for i, rep := range reps {
+		ids[i] = rep.ID
+	}
+
+	products, errs := loadersFor(ctx).products.LoadMany(ctx, ids)()
+	entities := make([]*model.Product, len(reps))
+	for i, product := range products {
+		if err := entityError(errs, i); err != nil {
+			return nil, fmt.Errorf("failed to resolve product entities: %w", err)
+		}
+		if product != nil {
+			entities[i] = productModel(product)
+		}
+	}
+	return entities, nil
+}
+
+// Entity returns EntityResolver implementation.
+func (r *Resolver) Entity() EntityResolver { return &entityResolver{r} }
+
+type entityResolver struct{ *Resolver }
@@ -0,0 +1,134 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/graph/model"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// hiddenCodes are the codes whose message may leak internals, replaced in production by a generic one.
+var hiddenCodes = map[codes.Code]string{
+	codes.Unknown:     "internal error",
+	codes.Internal:    "internal error",
+	codes.DataLoss:    "internal error",
+	codes.Unavailable: "service unavailable",
+}
+
+// ErrorPresenter turns errors carrying a gRPC status, including wrapped ones, into GraphQL errors
+// with extensions.code and the fieldViolations, resource and retryAfter of the status details.
+// In production the message of internal errors, and of any other error that is neither a gRPC
+// status nor a GraphQL error, is logged and replaced by a generic one.
+func ErrorPresenter(production bool) graphql.ErrorPresenterFunc {
+	return func(ctx context.Context, err error) *gqlerror.Error {
+		gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+
+		var withStatus interface{ GRPCStatus() *status.Status }
+		if !errors.As(err, &withStatus) {
+			var fromGraphQL *gqlerror.Error
+			if errors.As(err, &fromGraphQL) || !production {
+				return gqlErr
+			}
+			slog.Error("GraphQL request failed", "path", gqlErr.Path.String(), "error", err)
+			gqlErr.Message = hiddenCodes[codes.Internal]
+			gqlErr.Extensions = map[string]any{"code": codeName(codes.Internal)}
+			return gqlErr
+		}
+		st := withStatus.GRPCStatus()
+
+		if gqlErr.Extensions == nil {
+			gqlErr.Extensions = make(map[string]any)
+		}
+		gqlErr.Extensions["code"] = codeName(st.Code())
+		gqlErr.Message = st.Message()
+
+		if generic, ok := hiddenCodes[st.Code()]; ok && production {
+			slog.Error("GraphQL request failed", "path", gqlErr.Path.String(), "code", st.Code(), "error", err)
+			gqlErr.Message = generic
+			return gqlErr
+		}
+
+		for _, detail := range st.Details() {
+			switch detail := detail.(type) {
+			case *errdetails.BadRequest:
+				violations := make([]map[string]any, len(detail.FieldViolations))
+				for i, v := range detail.FieldViolations {
+					violations[i] = map[string]any{"field": fieldPath(v.Field), "message": v.Description}
+				}
+				gqlErr.Extensions["fieldViolations"] = violations
+			case *errdetails.ResourceInfo:
+				gqlErr.Extensions["resource"] = map[string]any{"type": detail.ResourceType, "name": detail.ResourceName}
+			case *errdetails.RetryInfo:
+				gqlErr.Extensions["retryAfter"] = int(math.Ceil(detail.RetryDelay.AsDuration().Seconds()))
+			}
+		}
+		return gqlErr
+	}
+}
+
+// codeName returns the SCREAMING_SNAKE_CASE name of code, e.g. NOT_FOUND.
+func codeName(code codes.Code) string {
+	var b strings.Builder
+	for i, r := range code.String() {
+		if i > 0 && unicode.IsUpper(r) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// fieldPath converts a proto field path such as price.currency_code to its GraphQL form, price.currencyCode.
+func fieldPath(protoPath string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range protoPath {
+		switch {
+		case r == '_':
+			upper = true
+		case upper:
+			b.WriteRune(unicode.ToUpper(r))
+			upper = false
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// userErrors returns the user errors described by an InvalidArgument status, so mutations can
+// report them in their payload; ok is false for any other error.
+func userErrors(err error) (errs []*model.UserError, ok bool) {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.InvalidArgument {
+		return nil, false
+	}
+
+	for _, detail := range st.Details() {
+		badRequest, isBadRequest := detail.(*errdetails.BadRequest)
+		if !isBadRequest {
+			continue
+		}
+		for _, v := range badRequest.FieldViolations {
+			field := fieldPath(v.Field)
+			code := v.Reason
+			if code == "" {
+				code = "INVALID"
+			}
+			errs = append(errs, &model.UserError{Field: &field, Message: v.Description, Code: code})
+		}
+	}
+	if len(errs) == 0 {
+		errs = append(errs, &model.UserError{Message: st.Message(), Code: "INVALID"})
+	}
+	return errs, true
+}
@@ -0,0 +1,38 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorPresenter(t *testing.T) {
+	tests := []struct {
+		name        string
+		production  bool
+		err         error
+		wantMessage string
+		wantCode    any
+	}{
+		{"status", false, status.Error(codes.NotFound, "product not found"), "product not found", "NOT_FOUND"},
+		{"wrapped status", true, fmt.Errorf("error getting product: %w", status.Error(codes.NotFound, "product not found")), "product not found", "NOT_FOUND"},
+		{"internal status", false, status.Error(codes.Internal, "cql: timeout"), "cql: timeout", "INTERNAL"},
+		{"internal status in production", true, status.Error(codes.Internal, "cql: timeout"), "internal error", "INTERNAL"},
+		{"GraphQL error in production", true, &gqlerror.Error{Message: "forbidden", Extensions: map[string]any{"code": "FORBIDDEN"}}, "forbidden", "FORBIDDEN"},
+		{"plain error", false, errors.New("dial tcp: connection refused"), "dial tcp: connection refused", nil},
+		{"plain error in production", true, errors.New("dial tcp: connection refused"), "internal error", "INTERNAL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ErrorPresenter(tt.production)(context.Background(), tt.err)
+			if got.Message != tt.wantMessage || got.Extensions["code"] != tt.wantCode {
+				t.Errorf("message %q, code %v; want %q, %v", got.Message, got.Extensions["code"], tt.wantMessage, tt.wantCode)
+			}
+		})
+	}
+}
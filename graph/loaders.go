@@ -0,0 +1,140 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/graph-gophers/dataloader/v7"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// batchSize matches the most ids BatchGetCategories and BatchGetProducts accept.
+const batchSize = 100
+
+type loadersKey struct{}
+
+// Loaders batches and caches lookups for the lifetime of a single response.
+type Loaders struct {
+	categories *dataloader.Loader[int64, *pb.Category]
+	products   *dataloader.Loader[int64, *pb.Product]
+}
+
+// NewLoaders returns response-scoped loaders fetching through conn.
+func NewLoaders(conn pb.ProductServiceClient) *Loaders {
+	return &Loaders{
+		categories: dataloader.NewBatchedLoader(batchGetCategories(conn),
+			dataloader.WithBatchCapacity[int64, *pb.Category](batchSize),
+			dataloader.WithWait[int64, *pb.Category](time.Millisecond),
+		),
+		products: dataloader.NewBatchedLoader(batchGetProducts(conn),
+			dataloader.WithBatchCapacity[int64, *pb.Product](batchSize),
+			dataloader.WithWait[int64, *pb.Product](time.Millisecond),
+		),
+	}
+}
+
+// LoaderExtension gives every response its own Loaders, so nothing is cached across operations.
+// A subscription gets new loaders for each event, rather than caching for as long as its
+// websocket stays open.
+type LoaderExtension struct {
+	conn pb.ProductServiceClient
+}
+
+var _ interface {
+	graphql.ResponseInterceptor
+	graphql.HandlerExtension
+} = LoaderExtension{}
+
+func NewLoaderExtension(conn pb.ProductServiceClient) LoaderExtension {
+	return LoaderExtension{conn: conn}
+}
+
+func (LoaderExtension) ExtensionName() string {
+	return "Loaders"
+}
+
+func (LoaderExtension) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// InterceptResponse runs once per query or mutation and once per subscription event, around
+// the resolution of the fields of the response.
+func (e LoaderExtension) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	return next(context.WithValue(ctx, loadersKey{}, NewLoaders(e.conn)))
+}
+
+// loadersFor returns the loaders of the response in ctx.
+func loadersFor(ctx context.Context) *Loaders {
+	return ctx.Value(loadersKey{}).(*Loaders)
+}
+
+// batchGetCategories resolves every category id of a batch with a single BatchGetCategories call.
+func batchGetCategories(conn pb.ProductServiceClient) dataloader.BatchFunc[int64, *pb.Category] {
+	return func(ctx context.Context, ids []int64) []*dataloader.Result[*pb.Category] {
+		results := make([]*dataloader.Result[*pb.Category], len(ids))
+
+		resp, err := conn.BatchGetCategories(ctx, &pb.BatchGetCategoriesRequest{Ids: ids})
+		if err != nil {
+			err = fmt.Errorf("failed to fetch categories: %w", err)
+			for i := range results {
+				results[i] = &dataloader.Result[*pb.Category]{Error: err}
+			}
+			return results
+		}
+
+		byID := make(map[int64]*pb.Category, len(resp.Categories))
+		for _, category := range resp.Categories {
+			byID[category.Id] = category
+		}
+		for i, id := range ids {
+			if category, ok := byID[id]; ok {
+				results[i] = &dataloader.Result[*pb.Category]{Data: category}
+			} else {
+				results[i] = &dataloader.Result[*pb.Category]{Error: status.Errorf(codes.NotFound, "category %d not found", id)}
+			}
+		}
+		return results
+	}
+}
+
+// batchGetProducts resolves every product id of a batch with a single BatchGetProducts call.
+func batchGetProducts(conn pb.ProductServiceClient) dataloader.BatchFunc[int64, *pb.Product] {
+	return func(ctx context.Context, ids []int64) []*dataloader.Result[*pb.Product] {
+		results := make([]*dataloader.Result[*pb.Product], len(ids))
+
+		resp, err := conn.BatchGetProducts(ctx, &pb.BatchGetProductsRequest{Ids: ids})
+		if err != nil {
+			err = fmt.Errorf("failed to fetch products: %w", err)
+			for i := range results {
+				results[i] = &dataloader.Result[*pb.Product]{Error: err}
+			}
+			return results
+		}
+
+		byID := make(map[int64]*pb.Product, len(resp.Products))
+		for _, product := range resp.Products {
+			byID[product.Id] = product
+		}
+		for i, id := range ids {
+			if product, ok := byID[id]; ok {
+				results[i] = &dataloader.Result[*pb.Product]{Data: product}
+			} else {
+				results[i] = &dataloader.Result[*pb.Product]{Error: status.Errorf(codes.NotFound, "product %d not found", id)}
+			}
+		}
+		return results
+	}
+}
+
+// entityError returns the error of the i-th key of a LoadMany call, ignoring NotFound: the
+// router expects null for entities that do not exist.
+func entityError(errs []error, i int) error {
+	if len(errs) <= i || errs[i] == nil || status.Code(errs[i]) == codes.NotFound {
+		return nil
+	}
+	return errs[i]
+}
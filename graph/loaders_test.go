@@ -0,0 +1,46 @@
+package graph
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/pb"
+	"google.golang.org/grpc"
+)
+
+// countingClient answers BatchGetCategories with every requested category and counts the calls.
+type countingClient struct {
+	pb.ProductServiceClient
+	calls atomic.Int32
+}
+
+func (c *countingClient) BatchGetCategories(ctx context.Context, req *pb.BatchGetCategoriesRequest, opts ...grpc.CallOption) (*pb.BatchGetCategoriesResponse, error) {
+	c.calls.Add(1)
+	resp := &pb.BatchGetCategoriesResponse{}
+	for _, id := range req.Ids {
+		resp.Categories = append(resp.Categories, &pb.Category{Id: id})
+	}
+	return resp, nil
+}
+
+func TestLoaderExtensionScopesLoadersToAResponse(t *testing.T) {
+	client := &countingClient{}
+	extension := NewLoaderExtension(client)
+
+	// Like a subscription delivering two events: each response resolves category 7 twice.
+	for range 2 {
+		extension.InterceptResponse(context.Background(), func(ctx context.Context) *graphql.Response {
+			for range 2 {
+				if _, err := loadersFor(ctx).categories.Load(ctx, 7)(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			return &graphql.Response{}
+		})
+	}
+	if got := client.calls.Load(); got != 2 {
+		t.Errorf("BatchGetCategories called %d times, want once per response", got)
+	}
+}
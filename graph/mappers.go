@@ -0,0 +1,63 @@
+package graph
+
+import (
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/graph/model"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/events"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/pb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// categoryMessage is implemented by every proto message describing a category.
+type categoryMessage interface {
+	GetId() int64
+	GetName() string
+	GetDescription() string
+	GetCreatedAt() *timestamppb.Timestamp
+}
+
+func categoryModel(c categoryMessage) *model.Category {
+	return &model.Category{
+		ID:          c.GetId(),
+		Name:        c.GetName(),
+		Description: c.GetDescription(),
+		CreatedAt:   c.GetCreatedAt().AsTime(),
+	}
+}
+
+func productModel(p *pb.Product) *model.Product {
+	return &model.Product{
+		ID:          p.Id,
+		CategoryID:  p.CategoryId,
+		Name:        p.Name,
+		Description: p.Description,
+		Price:       moneyModel(p.Price),
+		Stock:       p.Stock,
+		CreatedAt:   p.CreatedAt.AsTime(),
+		UpdatedAt:   p.UpdatedAt.AsTime(),
+	}
+}
+
+func productModels(products []*pb.Product) []*model.Product {
+	models := make([]*model.Product, len(products))
+	for i, p := range products {
+		models[i] = productModel(p)
+	}
+	return models
+}
+
+func inventoryModel(change *events.InventoryChange) *model.InventoryChange {
+	return &model.InventoryChange{
+		ProductID:  change.ProductID,
+		CategoryID: change.CategoryID,
+		StockCount: change.StockCount,
+		UpdatedAt:  change.UpdatedAt,
+	}
+}
+
+func moneyModel(m *pb.Money) model.Money {
+	return model.Money{Units: m.GetUnits(), Nanos: m.GetNanos(), Currency: m.GetCurrencyCode()}
+}
+
+func moneyProto(m model.Money) *pb.Money {
+	return &pb.Money{CurrencyCode: m.Currency, Units: m.Units, Nanos: m.Nanos}
+}
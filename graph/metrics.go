@@ -0,0 +1,115 @@
+package graph
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+var (
+	operations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "graphql_operations_total",
+		Help: "GraphQL responses, by operation name, type and result (ok or error). Subscriptions count every event.",
+	}, []string{"operation", "type", "result"})
+	operationErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "graphql_operation_errors_total",
+		Help: "Errors in GraphQL responses, by error code.",
+	}, []string{"code"})
+	operationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "graphql_operation_duration_seconds",
+		Help:    "Time from reading a query or mutation to writing its response, by operation name and type.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation", "type"})
+)
+
+// maxOperationNames bounds the operation names used as label values; clients pick the names,
+// so any beyond the limit are reported as "other".
+const maxOperationNames = 500
+
+// Metrics is an extension recording the rate, errors and duration of GraphQL operations.
+type Metrics struct {
+	mu    sync.Mutex
+	names map[string]bool
+}
+
+var _ interface {
+	graphql.ResponseInterceptor
+	graphql.HandlerExtension
+} = &Metrics{}
+
+func NewMetrics() *Metrics {
+	return &Metrics{names: make(map[string]bool)}
+}
+
+func (m *Metrics) ExtensionName() string {
+	return "Metrics"
+}
+
+func (m *Metrics) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// InterceptResponse also sees the responses of operations rejected before execution, such as
+// invalid or unsafelisted ones, which have no operation type.
+func (m *Metrics) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	resp := next(ctx)
+	if resp == nil {
+		return nil
+	}
+
+	name, opType := "", "unknown"
+	if graphql.HasOperationContext(ctx) {
+		opCtx := graphql.GetOperationContext(ctx)
+		name = opCtx.OperationName
+		if opCtx.Operation != nil {
+			opType = string(opCtx.Operation.Operation)
+			if name == "" {
+				name = opCtx.Operation.Name
+			}
+		}
+	}
+	name = m.label(name)
+
+	result := "ok"
+	if len(resp.Errors) > 0 {
+		result = "error"
+		for _, err := range resp.Errors {
+			code, _ := err.Extensions["code"].(string)
+			if code == "" {
+				code = "UNKNOWN"
+			}
+			operationErrors.WithLabelValues(code).Inc()
+		}
+	}
+	operations.WithLabelValues(name, opType, result).Inc()
+
+	if opType != string(ast.Subscription) {
+		if start := graphql.GetStartTime(ctx); !start.IsZero() {
+			operationDuration.WithLabelValues(name, opType).Observe(time.Since(start).Seconds())
+		}
+	}
+	return resp
+}
+
+// label returns the label value of an operation name.
+func (m *Metrics) label(name string) string {
+	if name == "" {
+		return "anonymous"
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.names[name] {
+		return name
+	}
+	if len(m.names) >= maxOperationNames {
+		return "other"
+	}
+	m.names[name] = true
+	return name
+}
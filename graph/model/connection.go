@@ -0,0 +1,9 @@
+package model
+
+// ProductConnection is a page of a category's products. Its totalCount is resolved
+// separately so the count is only queried when it is selected.
+type ProductConnection struct {
+	Edges      []*ProductEdge `json:"edges"`
+	PageInfo   *PageInfo      `json:"pageInfo"`
+	CategoryID int64          `json:"-"`
+}
@@ -2,11 +2,26 @@
 
 package model
 
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
 type Category struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	CreatedAt   string `json:"createdAt"`
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"createdAt"`
+	// Products of the category, newest first.
+	Products *ProductConnection `json:"products"`
+}
+
+func (Category) IsEntity() {}
+
+type CategoryByIDsInput struct {
+	ID int64 `json:"ID"`
 }
 
 type CreateCategoryInput struct {
@@ -14,12 +29,31 @@ type CreateCategoryInput struct {
 	Description string `json:"description"`
 }
 
+type CreateCategoryPayload struct {
+	// The created category, null when there are user errors.
+	Category   *Category    `json:"category,omitempty"`
+	UserErrors []*UserError `json:"userErrors"`
+}
+
 type CreateProductInput struct {
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
-	Stock       int32   `json:"stock"`
-	CategoryID  string  `json:"categoryId"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Price       Money  `json:"price"`
+	Stock       int32  `json:"stock"`
+	CategoryID  int64  `json:"categoryId"`
+}
+
+type CreateProductPayload struct {
+	// The created product, null when there are user errors.
+	Product    *Product     `json:"product,omitempty"`
+	UserErrors []*UserError `json:"userErrors"`
+}
+
+type InventoryChange struct {
+	ProductID  int64     `json:"productId"`
+	CategoryID int64     `json:"categoryId"`
+	StockCount int32     `json:"stockCount"`
+	UpdatedAt  time.Time `json:"updatedAt"`
 }
 
 type ListProductsResponse struct {
@@ -30,16 +64,105 @@ type ListProductsResponse struct {
 type Mutation struct {
 }
 
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor,omitempty"`
+	EndCursor       *string `json:"endCursor,omitempty"`
+}
+
 type Product struct {
-	ID          string  `json:"id"`
-	CategoryID  string  `json:"categoryId"`
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
-	Stock       int32   `json:"stock"`
-	CreatedAt   string  `json:"createdAt"`
-	UpdatedAt   string  `json:"updatedAt"`
+	ID          int64     `json:"id"`
+	CategoryID  int64     `json:"categoryId"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Price       Money     `json:"price"`
+	Stock       int32     `json:"stock"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+	Category    *Category `json:"category"`
+}
+
+func (Product) IsEntity() {}
+
+type ProductByIDsInput struct {
+	ID int64 `json:"ID"`
+}
+
+type ProductEdge struct {
+	Cursor string   `json:"cursor"`
+	Node   *Product `json:"node"`
 }
 
 type Query struct {
 }
+
+type Subscription struct {
+}
+
+// Fields left null are unchanged.
+type UpdateProductInput struct {
+	CategoryID  int64   `json:"categoryId"`
+	ProductID   int64   `json:"productId"`
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+	Price       *Money  `json:"price,omitempty"`
+	Stock       *int32  `json:"stock,omitempty"`
+}
+
+type UpdateProductPayload struct {
+	// The updated product, null when there are user errors.
+	Product    *Product     `json:"product,omitempty"`
+	UserErrors []*UserError `json:"userErrors"`
+}
+
+// A problem with the input of a mutation, to be shown next to the offending form field.
+type UserError struct {
+	// Path of the input field at fault, e.g. price.currencyCode, or null when the problem is not about a single field.
+	Field   *string `json:"field,omitempty"`
+	Message string  `json:"message"`
+	// Machine-readable reason: REQUIRED, INVALID, OUT_OF_RANGE or NOT_FOUND.
+	Code string `json:"code"`
+}
+
+// Roles a caller needs; an ADMIN has every role.
+type Role string
+
+const (
+	RoleAdmin  Role = "ADMIN"
+	RoleEditor Role = "EDITOR"
+)
+
+var AllRole = []Role{
+	RoleAdmin,
+	RoleEditor,
+}
+
+func (e Role) IsValid() bool {
+	switch e {
+	case RoleAdmin, RoleEditor:
+		return true
+	}
+	return false
+}
+
+func (e Role) String() string {
+	return string(e)
+}
+
+func (e *Role) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Role(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Role", str)
+	}
+	return nil
+}
+
+func (e Role) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
@@ -0,0 +1,155 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// MarshalDateTime writes t as an RFC 3339 string in UTC.
+func MarshalDateTime(t time.Time) graphql.Marshaler {
+	return graphql.WriterFunc(func(w io.Writer) {
+		io.WriteString(w, strconv.Quote(t.UTC().Format(time.RFC3339Nano)))
+	})
+}
+
+// UnmarshalDateTime reads an RFC 3339 string.
+func UnmarshalDateTime(v any) (time.Time, error) {
+	s, ok := v.(string)
+	if !ok {
+		return time.Time{}, errors.New("DateTime must be an RFC 3339 string")
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("DateTime must be an RFC 3339 string: %w", err)
+	}
+	return t, nil
+}
+
+// MarshalInt64 writes i as a string, since snowflake ids do not fit in a JavaScript number.
+func MarshalInt64(i int64) graphql.Marshaler {
+	return graphql.WriterFunc(func(w io.Writer) {
+		io.WriteString(w, strconv.Quote(strconv.FormatInt(i, 10)))
+	})
+}
+
+// UnmarshalInt64 reads a decimal string, or an integer small enough to be exact.
+func UnmarshalInt64(v any) (int64, error) {
+	switch v := v.(type) {
+	case string:
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("Int64 must be a decimal integer: %q", v)
+		}
+		return i, nil
+	case json.Number:
+		return v.Int64()
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	default:
+		return 0, fmt.Errorf("Int64 must be a string or an integer, got %T", v)
+	}
+}
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// Money is an exact amount in a currency, shaped like google.type.Money: Units is the whole
+// part, and Nanos the fractional part in billionths, with the same sign as Units.
+type Money struct {
+	Units    int64
+	Nanos    int32
+	Currency string
+}
+
+// String returns the decimal amount, e.g. "19.99".
+func (m Money) String() string {
+	var b strings.Builder
+	if m.Units < 0 || m.Nanos < 0 {
+		b.WriteByte('-')
+	}
+	units, nanos := m.Units, m.Nanos
+	if units < 0 {
+		units = -units
+	}
+	if nanos < 0 {
+		nanos = -nanos
+	}
+	b.WriteString(strconv.FormatInt(units, 10))
+	if nanos != 0 {
+		b.WriteByte('.')
+		b.WriteString(strings.TrimRight(fmt.Sprintf("%09d", nanos), "0"))
+	}
+	return b.String()
+}
+
+// ParseMoney parses a decimal amount with at most nine fractional digits.
+func ParseMoney(amount, currency string) (Money, error) {
+	if !currencyCode.MatchString(currency) {
+		return Money{}, fmt.Errorf("currency must be an ISO 4217 code: %q", currency)
+	}
+
+	s, negative := strings.CutPrefix(amount, "-")
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" || len(frac) > 9 || strings.ContainsAny(whole+frac, "+-") {
+		return Money{}, fmt.Errorf("invalid amount %q", amount)
+	}
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid amount %q", amount)
+	}
+	var nanos int64
+	if frac != "" {
+		if nanos, err = strconv.ParseInt(frac+strings.Repeat("0", 9-len(frac)), 10, 32); err != nil {
+			return Money{}, fmt.Errorf("invalid amount %q", amount)
+		}
+	}
+	if negative {
+		units, nanos = -units, -nanos
+	}
+	return Money{Units: units, Nanos: int32(nanos), Currency: currency}, nil
+}
+
+// MarshalGQL writes m as {"amount": "19.99", "currency": "USD"}. The amount is a string so it is never rounded.
+func (m Money) MarshalGQL(w io.Writer) {
+	data, _ := json.Marshal(map[string]string{"amount": m.String(), "currency": m.Currency})
+	w.Write(data)
+}
+
+// UnmarshalGQL reads {"amount": "19.99", "currency": "USD"}; the amount may also be a number.
+func (m *Money) UnmarshalGQL(v any) error {
+	fields, ok := v.(map[string]any)
+	if !ok {
+		return errors.New(`Money must be an object like {amount: "19.99", currency: "USD"}`)
+	}
+
+	var amount string
+	switch a := fields["amount"].(type) {
+	case string:
+		amount = a
+	case json.Number:
+		amount = a.String()
+	case int64:
+		amount = strconv.FormatInt(a, 10)
+	case float64:
+		amount = strconv.FormatFloat(a, 'f', -1, 64)
+	default:
+		return errors.New("Money amount must be a decimal string")
+	}
+	currency, _ := fields["currency"].(string)
+
+	parsed, err := ParseMoney(amount, currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
@@ -0,0 +1,91 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		want     Money
+		wantErr  bool
+	}{
+		{"19.99", "USD", Money{Units: 19, Nanos: 990_000_000, Currency: "USD"}, false},
+		{"19", "USD", Money{Units: 19, Currency: "USD"}, false},
+		{"0.000000001", "USD", Money{Nanos: 1, Currency: "USD"}, false},
+		{"-0.5", "EUR", Money{Nanos: -500_000_000, Currency: "EUR"}, false},
+		{"-19.99", "EUR", Money{Units: -19, Nanos: -990_000_000, Currency: "EUR"}, false},
+		{"0.0000000001", "USD", Money{}, true},
+		{"19.99", "usd", Money{}, true},
+		{"19.99", "", Money{}, true},
+		{"", "USD", Money{}, true},
+		{".5", "USD", Money{}, true},
+		{"1e3", "USD", Money{}, true},
+		{"--1", "USD", Money{}, true},
+		{"1.-5", "USD", Money{}, true},
+		{"99999999999999999999", "USD", Money{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.amount+" "+tt.currency, func(t *testing.T) {
+			got, err := ParseMoney(tt.amount, tt.currency)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMoney() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseMoney() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{Money{Units: 19, Nanos: 990_000_000}, "19.99"},
+		{Money{Units: 19}, "19"},
+		{Money{Nanos: -500_000_000}, "-0.5"},
+		{Money{Units: -19, Nanos: -1}, "-19.000000001"},
+		{Money{}, "0"},
+	}
+	for _, tt := range tests {
+		if got := tt.money.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.money, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyGQL(t *testing.T) {
+	var buf bytes.Buffer
+	Money{Units: 19, Nanos: 990_000_000, Currency: "USD"}.MarshalGQL(&buf)
+	if got, want := buf.String(), `{"amount":"19.99","currency":"USD"}`; got != want {
+		t.Errorf("MarshalGQL() = %s, want %s", got, want)
+	}
+
+	inputs := []map[string]any{
+		{"amount": "19.99", "currency": "USD"},
+		{"amount": json.Number("19.99"), "currency": "USD"},
+		{"amount": 19.99, "currency": "USD"},
+	}
+	for _, input := range inputs {
+		var m Money
+		if err := m.UnmarshalGQL(input); err != nil {
+			t.Fatalf("UnmarshalGQL(%v) error = %v", input, err)
+		}
+		if m != (Money{Units: 19, Nanos: 990_000_000, Currency: "USD"}) {
+			t.Errorf("UnmarshalGQL(%v) = %+v", input, m)
+		}
+	}
+
+	var m Money
+	if err := m.UnmarshalGQL("19.99"); err == nil {
+		t.Error("UnmarshalGQL() of a bare string succeeded")
+	}
+	if err := m.UnmarshalGQL(map[string]any{"amount": true, "currency": "USD"}); err == nil {
+		t.Error("UnmarshalGQL() of a boolean amount succeeded")
+	}
+}
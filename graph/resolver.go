@@ -1,11 +1,15 @@
 package graph
 
-import "github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/pb"
+import (
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/events"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/pb"
+)
 
 // This file will not be regenerated automatically.
 //
 // It serves as dependency injection for your app, add any dependencies you require here.
 
 type Resolver struct {
-	Conn pb.ProductServiceClient
+	Conn   pb.ProductServiceClient
+	Events *events.Broker
 }
@@ -1,185 +1,211 @@
 package graph
 
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.66
+
 import (
 	"context"
 	"fmt"
-	"strconv"
-	"time"
+	"math"
 
 	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/graph/model"
-	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/helpers"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/events"
 	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/pb"
 )
 
+// Products is the resolver for the products field.
+func (r *categoryResolver) Products(ctx context.Context, obj *model.Category, first *int32, after *string) (*model.ProductConnection, error) {
+	return r.productsConnection(ctx, obj.ID, first, after)
+}
+
 // CreateCategory is the resolver for the createCategory field.
-func (r *mutationResolver) CreateCategory(ctx context.Context, input model.CreateCategoryInput) (*model.Category, error) {
-	createCategoryRequest := &pb.CreateCategoryRequest{
+func (r *mutationResolver) CreateCategory(ctx context.Context, input model.CreateCategoryInput) (*model.CreateCategoryPayload, error) {
+	createCategoryresponse, err := r.Conn.CreateCategory(ctx, &pb.CreateCategoryRequest{
 		Name:        input.Name,
 		Description: input.Description,
-	}
-
-	createCategoryresponse, err := r.Conn.CreateCategory(ctx, createCategoryRequest)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error creating category: %v", err)
+		if errs, ok := userErrors(err); ok {
+			return &model.CreateCategoryPayload{UserErrors: errs}, nil
+		}
+		return nil, fmt.Errorf("error creating category: %w", err)
 	}
 
-	return &model.Category{
-		ID:          fmt.Sprintf("%d", createCategoryresponse.Id),
-		Name:        createCategoryRequest.Name,
-		Description: createCategoryRequest.Description,
-		CreatedAt:   createCategoryresponse.CreatedAt.AsTime().Format(time.RFC3339),
-	}, nil
-
+	return &model.CreateCategoryPayload{Category: categoryModel(createCategoryresponse), UserErrors: []*model.UserError{}}, nil
 }
 
 // CreateProduct is the resolver for the createProduct field.
-func (r *mutationResolver) CreateProduct(ctx context.Context, input model.CreateProductInput) (*model.Product, error) {
-	categoryIdInt, err := strconv.ParseUint(input.CategoryID, 10, 64)
+func (r *mutationResolver) CreateProduct(ctx context.Context, input model.CreateProductInput) (*model.CreateProductPayload, error) {
+	createdProductRes, err := r.Conn.CreateProduct(ctx, &pb.CreateProductRequest{
+		Name:        input.Name,
+		Description: input.Description,
+		Price:       moneyProto(input.Price),
+		Stock:       input.Stock,
+		CategoryId:  input.CategoryID,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error parsing category ID: %v", err)
+		if errs, ok := userErrors(err); ok {
+			return &model.CreateProductPayload{UserErrors: errs}, nil
+		}
+		return nil, fmt.Errorf("error creating product: %w", err)
 	}
 
-	createProductRequest := &pb.CreateProductRequest{
+	return &model.CreateProductPayload{Product: productModel(createdProductRes.Product), UserErrors: []*model.UserError{}}, nil
+}
+
+// UpdateProduct is the resolver for the updateProduct field.
+func (r *mutationResolver) UpdateProduct(ctx context.Context, input model.UpdateProductInput) (*model.UpdateProductPayload, error) {
+	req := &pb.UpdateProductRequest{
+		CategoryId:  input.CategoryID,
+		ProductId:   input.ProductID,
 		Name:        input.Name,
 		Description: input.Description,
-		Price:       float32(input.Price),
 		Stock:       input.Stock,
-		CategoryId:  int64(categoryIdInt),
 	}
-
-	createdProductRes, err := r.Conn.CreateProduct(ctx, createProductRequest)
+	if input.Price != nil {
+		req.Price = moneyProto(*input.Price)
+	}
+	updatedProductRes, err := r.Conn.UpdateProduct(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("error creating product: %v", err)
-
+		if errs, ok := userErrors(err); ok {
+			return &model.UpdateProductPayload{UserErrors: errs}, nil
+		}
+		return nil, fmt.Errorf("error updating product: %w", err)
 	}
 
-	return &model.Product{
-		ID:          fmt.Sprintf("%d", createdProductRes.Product.Id),
-		CategoryID:  fmt.Sprintf("%d", createdProductRes.Product.CategoryId),
-		Name:        createdProductRes.Product.Name,
-		Description: createProductRequest.Description,
-		Price:       float64(createProductRequest.Price),
-		Stock:       createProductRequest.Stock,
-		CreatedAt:   createdProductRes.Product.CreatedAt.AsTime().Format(time.RFC3339),
-		UpdatedAt:   createdProductRes.Product.UpdatedAt.AsTime().Format(time.RFC3339),
-	}, nil
-
+	return &model.UpdateProductPayload{Product: productModel(updatedProductRes.Product), UserErrors: []*model.UserError{}}, nil
 }
 
-// GetCategory is the resolver for the getCategory field.
-func (r *queryResolver) GetCategory(ctx context.Context, id string) (*model.Category, error) {
-	categoryId, err := strconv.ParseUint(id, 10, 64)
+// Category is the resolver for the category field.
+func (r *productResolver) Category(ctx context.Context, obj *model.Product) (*model.Category, error) {
+	category, err := loadersFor(ctx).categories.Load(ctx, obj.CategoryID)()
 	if err != nil {
-		return nil, fmt.Errorf("error parsing category ID: %v", err)
+		return nil, err
 	}
+	return categoryModel(category), nil
+}
 
-	getCategoryRequest := &pb.GetCategoryRequest{
-		Id: int64(categoryId),
-	}
-	category, err := r.Conn.GetCategory(ctx, getCategoryRequest)
+// TotalCount is the resolver for the totalCount field.
+func (r *productConnectionResolver) TotalCount(ctx context.Context, obj *model.ProductConnection) (int32, error) {
+	resp, err := r.Conn.CountProducts(ctx, &pb.CountProductsRequest{CategoryId: obj.CategoryID})
 	if err != nil {
-		return nil, fmt.Errorf("error getting category: %v", err)
+		return 0, fmt.Errorf("failed to count products: %w", err)
 	}
-
-	return &model.Category{
-		ID:          fmt.Sprintf("%d", category.Id),
-		Name:        category.Name,
-		Description: category.Description,
-		CreatedAt:   category.CreatedAt.AsTime().Format(time.RFC3339),
-	}, nil
+	if resp.Count > math.MaxInt32 {
+		return math.MaxInt32, nil
+	}
+	return int32(resp.Count), nil
 }
 
-// GetProduct is the resolver for the getProduct field.
-func (r *queryResolver) GetProduct(ctx context.Context, categoryID string, productID string) (*model.Product, error) {
-	categoryIdInt, err := strconv.ParseUint(categoryID, 10, 64)
-	if err != nil {
-		return nil, fmt.Errorf("error parsing category ID: %v", err)
-	}
-	productIdInt, err := strconv.ParseUint(productID, 10, 64)
+// GetCategory is the resolver for the getCategory field.
+func (r *queryResolver) GetCategory(ctx context.Context, id int64) (*model.Category, error) {
+	category, err := r.Conn.GetCategory(ctx, &pb.GetCategoryRequest{Id: id})
 	if err != nil {
-		return nil, fmt.Errorf("error parsing product ID: %v", err)
+		return nil, fmt.Errorf("error getting category: %w", err)
 	}
 
+	return categoryModel(category), nil
+}
+
+// GetProduct is the resolver for the getProduct field.
+func (r *queryResolver) GetProduct(ctx context.Context, categoryID int64, productID int64) (*model.Product, error) {
 	productRes, err := r.Conn.GetProduct(ctx, &pb.GetProductRequest{
-		CategoryId: int64(categoryIdInt),
-		ProductId:  int64(productIdInt),
+		CategoryId: categoryID,
+		ProductId:  productID,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("error getting product: %v", err)
+		return nil, fmt.Errorf("error getting product: %w", err)
 	}
 
-	return &model.Product{
-		ID:          fmt.Sprintf("%d", productRes.Product.Id),
-		CategoryID:  fmt.Sprintf("%d", productRes.Product.CategoryId),
-		Name:        productRes.Product.Name,
-		Description: productRes.Product.Description,
-		Price:       float64(productRes.Product.Price),
-		Stock:       productRes.Product.Stock,
-		CreatedAt:   productRes.Product.CreatedAt.AsTime().Format(time.RFC3339),
-		UpdatedAt:   productRes.Product.UpdatedAt.AsTime().Format(time.RFC3339),
-	}, nil
+	return productModel(productRes.Product), nil
 }
 
 // ListProducts is the resolver for the listProducts field.
-func (r *queryResolver) ListProducts(ctx context.Context, categoryID string, pageSize *int32, pagingState *string) (*model.ListProductsResponse, error) {
-	if categoryID == "" {
-		return nil, fmt.Errorf("categoryID is required")
-	}
-
-	// Convert categoryID to bigint (int64)
-	categoryId, err := strconv.ParseInt(categoryID, 10, 64)
+func (r *queryResolver) ListProducts(ctx context.Context, categoryID int64, pageSize *int32, pagingState *string) (*model.ListProductsResponse, error) {
+	limit, err := pageLimit("pageSize", pageSize)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing category ID: %v", err)
+		return nil, err
 	}
 
-	// Set default page size if not provided
-	limit := int32(10)
-	if pageSize != nil && *pageSize > 0 {
-		limit = *pageSize
+	req := &pb.ListProductsRequest{
+		CategoryId: categoryID,
+		PageSize:   limit,
 	}
-
-	// Decode paging state if provided
-	var decodedPagingState []byte
 	if pagingState != nil {
-		decodedPagingState = helpers.DecodePagingState(pagingState)
+		req.PageToken = *pagingState
 	}
 
-	resp, err := r.Conn.ListProducts(ctx, &pb.ListProductsRequest{
-		CategoryId:  categoryId, // Using bigint (int64)
-		PagingState: decodedPagingState,
-		PageSize:    limit,
-	})
-
+	resp, err := r.Conn.ListProducts(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch products: %v", err)
+		return nil, fmt.Errorf("failed to fetch products: %w", err)
 	}
 
-	// Convert response to GraphQL model
-	products := make([]*model.Product, len(resp.Products))
-	for i, p := range resp.Products {
-		products[i] = &model.Product{
-			ID:          strconv.FormatInt(p.Id, 10),
-			CategoryID:  strconv.FormatInt(p.CategoryId, 10),
-			Name:        p.Name,
-			Description: p.Description,
-			Price:       float64(p.Price),
-			Stock:       p.Stock,
-			CreatedAt:   p.CreatedAt.AsTime().Format(time.RFC3339),
-			UpdatedAt:   p.UpdatedAt.AsTime().Format(time.RFC3339),
-		}
+	response := &model.ListProductsResponse{Products: productModels(resp.Products)}
+	if resp.NextPageToken != "" {
+		response.PagingState = &resp.NextPageToken
 	}
+	return response, nil
+}
+
+// ProductsConnection is the resolver for the productsConnection field.
+func (r *queryResolver) ProductsConnection(ctx context.Context, categoryID int64, first *int32, after *string) (*model.ProductConnection, error) {
+	return r.productsConnection(ctx, categoryID, first, after)
+}
+
+// ProductCreated is the resolver for the productCreated field.
+func (r *subscriptionResolver) ProductCreated(ctx context.Context, categoryID *int64) (<-chan *model.Product, error) {
+	return r.subscribeProducts(ctx, events.ProductCreated, categoryID)
+}
 
-	return &model.ListProductsResponse{
-		Products:    products,
-		PagingState: helpers.EncodePagingState(resp.PagingState),
-	}, nil
+// ProductUpdated is the resolver for the productUpdated field.
+func (r *subscriptionResolver) ProductUpdated(ctx context.Context, categoryID *int64) (<-chan *model.Product, error) {
+	return r.subscribeProducts(ctx, events.ProductUpdated, categoryID)
 }
 
+// InventoryChanged is the resolver for the inventoryChanged field.
+func (r *subscriptionResolver) InventoryChanged(ctx context.Context, productID int64) (<-chan *model.InventoryChange, error) {
+	in := r.subscribe(ctx, func(event events.Event) bool {
+		return event.Type == events.InventoryChanged && event.Inventory != nil && event.Inventory.ProductID == productID
+	})
+
+	out := make(chan *model.InventoryChange)
+	go func() {
+		defer close(out)
+		for event := range in {
+			select {
+			case out <- inventoryModel(event.Inventory):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// Category returns CategoryResolver implementation.
+func (r *Resolver) Category() CategoryResolver { return &categoryResolver{r} }
+
 // Mutation returns MutationResolver implementation.
 func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
 
+// Product returns ProductResolver implementation.
+func (r *Resolver) Product() ProductResolver { return &productResolver{r} }
+
+// ProductConnection returns ProductConnectionResolver implementation.
+func (r *Resolver) ProductConnection() ProductConnectionResolver {
+	return &productConnectionResolver{r}
+}
+
 // Query returns QueryResolver implementation.
 func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
 
+// Subscription returns SubscriptionResolver implementation.
+func (r *Resolver) Subscription() SubscriptionResolver { return &subscriptionResolver{r} }
+
+type categoryResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
+type productResolver struct{ *Resolver }
+type productConnectionResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
+type subscriptionResolver struct{ *Resolver }
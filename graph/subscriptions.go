@@ -0,0 +1,42 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/graph/model"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/events"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/tenant"
+)
+
+// subscribe returns the events accepted by filter that belong to the caller's tenant.
+func (r *Resolver) subscribe(ctx context.Context, filter func(events.Event) bool) <-chan events.Event {
+	tenantID, _ := tenant.FromContext(ctx)
+	return r.Events.Subscribe(ctx, func(event events.Event) bool {
+		return event.Tenant == tenantID && filter(event)
+	})
+}
+
+// subscribeProducts streams the products of events of eventType, restricted to a category when categoryID is set.
+func (r *Resolver) subscribeProducts(ctx context.Context, eventType string, categoryID *int64) (<-chan *model.Product, error) {
+	var categoryId int64
+	if categoryID != nil {
+		categoryId = *categoryID
+	}
+
+	in := r.subscribe(ctx, func(event events.Event) bool {
+		return event.Type == eventType && event.Product != nil && (categoryId == 0 || event.Product.CategoryId == categoryId)
+	})
+
+	out := make(chan *model.Product)
+	go func() {
+		defer close(out)
+		for event := range in {
+			select {
+			case out <- productModel(event.Product):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
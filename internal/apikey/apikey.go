@@ -0,0 +1,81 @@
+// Package apikey authenticates partners by API key and rate limits each key with a token bucket,
+// on the gateway and on the gRPC server.
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+const (
+	// HeaderName is the HTTP header partners send their key in.
+	HeaderName = "X-API-Key"
+	// MetadataKey is the gRPC metadata key carrying the key between services.
+	MetadataKey = "x-api-key"
+
+	idPrefix = "pk_"
+)
+
+// ErrInvalid is returned for keys that are malformed, unknown or revoked.
+var ErrInvalid = errors.New("invalid API key")
+
+// Generate returns a new key, formatted <id>.<secret>, with its id and the hash of its secret.
+func Generate() (id, key string, secretHash []byte, err error) {
+	idBytes := make([]byte, 8)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", "", nil, err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", nil, err
+	}
+
+	id = idPrefix + hex.EncodeToString(idBytes)
+	encoded := base64.RawURLEncoding.EncodeToString(secret)
+	return id, id + "." + encoded, hashSecret(encoded), nil
+}
+
+// parse splits a key into its id and secret.
+func parse(key string) (id, secret string, err error) {
+	id, secret, ok := strings.Cut(key, ".")
+	if !ok || !strings.HasPrefix(id, idPrefix) || secret == "" {
+		return "", "", ErrInvalid
+	}
+	return id, secret, nil
+}
+
+func hashSecret(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
+
+func secretMatches(secret string, hash []byte) bool {
+	return subtle.ConstantTimeCompare(hashSecret(secret), hash) == 1
+}
+
+// Key is the authenticated key of a request.
+type Key struct {
+	ID   string
+	Name string
+	// raw is forwarded to the gRPC server, which authenticates and limits the key itself.
+	raw string
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying key.
+func NewContext(ctx context.Context, key *Key) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+// FromContext returns the API key of the request in ctx, if it sent one.
+func FromContext(ctx context.Context) (*Key, bool) {
+	key, ok := ctx.Value(contextKey{}).(*Key)
+	return key, ok
+}
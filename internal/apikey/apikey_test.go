@@ -0,0 +1,282 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/repository"
+)
+
+// fakeRepo serves API keys from memory; the other repository methods are not used.
+type fakeRepo struct {
+	repository.Repository
+	mu   sync.Mutex
+	keys map[string]repository.APIKey
+	gets int
+}
+
+func (r *fakeRepo) GetAPIKey(ctx context.Context, id string) (*repository.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.gets++
+	key, ok := r.keys[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &key, nil
+}
+
+func (r *fakeRepo) update(id string, fn func(key *repository.APIKey)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := r.keys[id]
+	fn(&key)
+	r.keys[id] = key
+}
+
+// issue stores a new key with the given limits and returns it.
+func issue(t *testing.T, repo *fakeRepo, requestsPerSecond float64, burst int32) (id, raw string) {
+	t.Helper()
+	id, raw, secretHash, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.keys[id] = repository.APIKey{ID: id, SecretHash: secretHash, Name: "partner", RequestsPerSecond: requestsPerSecond, Burst: burst}
+	return id, raw
+}
+
+func TestGenerate(t *testing.T) {
+	id, key, secretHash, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsedID, secret, err := parse(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsedID != id || !strings.HasPrefix(id, idPrefix) {
+		t.Errorf("parse(%q) id = %q, want %q", key, parsedID, id)
+	}
+	if !secretMatches(secret, secretHash) {
+		t.Error("secret does not match its hash")
+	}
+	if secretMatches(secret+"x", secretHash) {
+		t.Error("altered secret matches the hash")
+	}
+
+	_, other, _, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == key {
+		t.Error("Generate() returned the same key twice")
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		key     string
+		id      string
+		wantErr bool
+	}{
+		{"pk_0123.secret", "pk_0123", false},
+		{"pk_0123.sec.ret", "pk_0123", false},
+		{"pk_0123", "", true},
+		{"pk_0123.", "", true},
+		{"sk_0123.secret", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		id, _, err := parse(tt.key)
+		if (err != nil) != tt.wantErr || id != tt.id {
+			t.Errorf("parse(%q) = %q, %v; want %q, error %v", tt.key, id, err, tt.id, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrInvalid) {
+			t.Errorf("parse(%q) error = %v, want ErrInvalid", tt.key, err)
+		}
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	repo := &fakeRepo{keys: make(map[string]repository.APIKey)}
+	id, raw := issue(t, repo, 10, 10)
+	revokedID, revoked := issue(t, repo, 10, 10)
+	revokedAt := time.Now()
+	repo.update(revokedID, func(key *repository.APIKey) { key.RevokedAt = &revokedAt })
+	_, zeroRate := issue(t, repo, 0, 10)
+	_, unknown, _, _ := Generate()
+	limiter := NewLimiter(repo, time.Minute, 1)
+
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr bool
+	}{
+		{"valid", raw, false},
+		{"wrong secret", id + ".nope", true},
+		{"malformed", "nope", true},
+		{"unknown", unknown, true},
+		{"revoked", revoked, true},
+		{"zero rate", zeroRate, true},
+	}
+	for _, tt := range tests {
+		key, err := limiter.Authenticate(context.Background(), tt.raw)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalid) {
+				t.Errorf("%s: error = %v, want ErrInvalid", tt.name, err)
+			}
+			continue
+		}
+		if err != nil || key.ID != id || key.Name != "partner" {
+			t.Errorf("%s: Authenticate() = %+v, %v", tt.name, key, err)
+		}
+	}
+}
+
+func TestAllow(t *testing.T) {
+	tests := []struct {
+		name   string
+		burst  int32
+		factor float64
+		want   int // requests allowed at once
+	}{
+		{"burst", 3, 1, 3},
+		{"scaled by factor", 3, 2, 6},
+		{"no factor", 3, 0, 3},
+		{"burst rounded down to one", 1, 0.5, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepo{keys: make(map[string]repository.APIKey)}
+			_, raw := issue(t, repo, 0.001, tt.burst)
+			limiter := NewLimiter(repo, time.Minute, tt.factor)
+			key, err := limiter.Authenticate(context.Background(), raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := range tt.want {
+				if ok, _ := limiter.Allow(key); !ok {
+					t.Fatalf("request %d rejected, want %d allowed", i+1, tt.want)
+				}
+			}
+			ok, retryAfter := limiter.Allow(key)
+			if ok || retryAfter <= 0 {
+				t.Errorf("Allow() after the burst = %v, %s; want a rejection with a delay", ok, retryAfter)
+			}
+		})
+	}
+}
+
+func TestLimiterAppliesChangedLimits(t *testing.T) {
+	repo := &fakeRepo{keys: make(map[string]repository.APIKey)}
+	id, raw := issue(t, repo, 0.001, 1)
+	limiter := NewLimiter(repo, time.Millisecond, 1)
+
+	key, err := limiter.Authenticate(context.Background(), raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiter.Allow(key)
+	if ok, _ := limiter.Allow(key); ok {
+		t.Fatal("Allow() succeeded past the burst")
+	}
+
+	repo.update(id, func(key *repository.APIKey) { key.RequestsPerSecond, key.Burst = 1000, 1000 })
+	time.Sleep(5 * time.Millisecond)
+	if _, err := limiter.Authenticate(context.Background(), raw); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond) // refill at the new rate
+	if ok, _ := limiter.Allow(key); !ok {
+		t.Error("Allow() rejected after the limits were raised")
+	}
+}
+
+func TestLimiterEvictsRevokedAndIdleKeys(t *testing.T) {
+	repo := &fakeRepo{keys: make(map[string]repository.APIKey)}
+	revokedID, revoked := issue(t, repo, 1000, 1)
+	idleID, idle := issue(t, repo, 1000, 1)
+	_, active := issue(t, repo, 1000, 1)
+	limiter := NewLimiter(repo, time.Millisecond, 1)
+	for _, raw := range []string{revoked, idle} {
+		if _, err := limiter.Authenticate(context.Background(), raw); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	revokedAt := time.Now()
+	repo.update(revokedID, func(key *repository.APIKey) { key.RevokedAt = &revokedAt })
+	time.Sleep(5 * time.Millisecond)
+	if _, err := limiter.Authenticate(context.Background(), revoked); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("revoked key: error = %v, want ErrInvalid", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := limiter.Authenticate(context.Background(), active); err != nil {
+		t.Fatal(err)
+	}
+
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	for _, id := range []string{revokedID, idleID} {
+		if _, ok := limiter.buckets[id]; ok {
+			t.Errorf("bucket of %s was not evicted", id)
+		}
+		if _, ok := limiter.keys[id]; ok {
+			t.Errorf("cache entry of %s was not evicted", id)
+		}
+	}
+	if len(limiter.buckets) != 1 {
+		t.Errorf("%d buckets, want only the active key's", len(limiter.buckets))
+	}
+}
+
+func TestLimiterCachesKeys(t *testing.T) {
+	repo := &fakeRepo{keys: make(map[string]repository.APIKey)}
+	_, raw := issue(t, repo, 10, 10)
+	_, unknown, _, _ := Generate()
+	limiter := NewLimiter(repo, time.Minute, 1)
+
+	for range 3 {
+		limiter.Authenticate(context.Background(), raw)
+		limiter.Authenticate(context.Background(), unknown)
+	}
+	// Unknown ids are looked up every time.
+	if repo.gets != 4 {
+		t.Errorf("%d repository lookups, want 4", repo.gets)
+	}
+}
+
+func TestLimiterEvictsLeastRecentlyUsedKeys(t *testing.T) {
+	repo := &fakeRepo{keys: make(map[string]repository.APIKey)}
+	firstID, first := issue(t, repo, 10, 10)
+	secondID, second := issue(t, repo, 10, 10)
+	thirdID, third := issue(t, repo, 10, 10)
+	limiter := NewLimiter(repo, time.Minute, 1)
+	limiter.maxKeys = 2
+
+	for _, raw := range []string{first, second, first, third} {
+		if _, err := limiter.Authenticate(context.Background(), raw); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for range 3 {
+		_, unknown, _, _ := Generate()
+		limiter.Authenticate(context.Background(), unknown)
+	}
+
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	for id, want := range map[string]bool{firstID: true, secondID: false, thirdID: true} {
+		if _, ok := limiter.keys[id]; ok != want {
+			t.Errorf("key %s cached = %v, want %v", id, ok, want)
+		}
+	}
+	if limiter.lru.Len() != len(limiter.keys) {
+		t.Errorf("%d entries in the LRU list, %d in the map", limiter.lru.Len(), len(limiter.keys))
+	}
+}
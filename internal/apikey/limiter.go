@@ -0,0 +1,200 @@
+package apikey
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/repository"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// maxCachedKeys bounds the key cache, the least recently used key being evicted first.
+	maxCachedKeys = 10_000
+
+	defaultCacheTTL = 30 * time.Second
+)
+
+// cachedKey is an entry of the key cache. Unknown ids are not cached, so that requests with
+// made-up ids cannot evict the keys in use.
+type cachedKey struct {
+	id        string
+	key       *repository.APIKey
+	fetchedAt time.Time
+}
+
+// bucket is the token bucket of a key and the limits it was built from.
+type bucket struct {
+	*rate.Limiter
+	requestsPerSecond float64
+	burst             int32
+}
+
+// Limiter authenticates keys against the repository, caching them for a while, and keeps a
+// token bucket per key.
+type Limiter struct {
+	repo     repository.Repository
+	cacheTTL time.Duration
+	// factor scales the rate and burst of every key, e.g. to count the RPCs of a request.
+	factor float64
+
+	mu        sync.Mutex
+	keys      map[string]*list.Element // of *cachedKey in lru
+	lru       *list.List               // most recently used first
+	maxKeys   int
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewLimiter returns a Limiter of keys stored in repo; revoked keys are rejected and limit
+// changes applied once their cache entry, kept for cacheTTL (30 seconds when zero), expires.
+// A factor that is not positive counts as 1.
+func NewLimiter(repo repository.Repository, cacheTTL time.Duration, factor float64) *Limiter {
+	if cacheTTL <= 0 {
+		cacheTTL = defaultCacheTTL
+	}
+	if factor <= 0 {
+		factor = 1
+	}
+	return &Limiter{
+		repo:     repo,
+		cacheTTL: cacheTTL,
+		factor:   factor,
+		keys:     make(map[string]*list.Element),
+		lru:      list.New(),
+		maxKeys:  maxCachedKeys,
+		buckets:  make(map[string]*bucket),
+	}
+}
+
+// Authenticate returns the key raw identifies, or ErrInvalid.
+func (l *Limiter) Authenticate(ctx context.Context, raw string) (*Key, error) {
+	id, secret, err := parse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := l.lookup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil || stored.RevokedAt != nil || !secretMatches(secret, stored.SecretHash) {
+		return nil, ErrInvalid
+	}
+	if stored.RequestsPerSecond <= 0 || stored.Burst < 1 {
+		// Such a bucket would reject every request; fail loudly rather than silently.
+		slog.Error("API key has invalid limits", "id", stored.ID, "requests_per_second", stored.RequestsPerSecond, "burst", stored.Burst)
+		return nil, ErrInvalid
+	}
+	return &Key{ID: stored.ID, Name: stored.Name, raw: raw}, nil
+}
+
+// Allow takes a token from the bucket of key. When it is empty, it returns how long to wait.
+func (l *Limiter) Allow(key *Key) (ok bool, retryAfter time.Duration) {
+	l.mu.Lock()
+	bucket := l.buckets[key.ID]
+	l.mu.Unlock()
+	if bucket == nil {
+		return true, 0
+	}
+
+	now := time.Now()
+	reservation := bucket.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+func (l *Limiter) lookup(ctx context.Context, id string) (*repository.APIKey, error) {
+	l.mu.Lock()
+	if elem, ok := l.keys[id]; ok {
+		if cached := elem.Value.(*cachedKey); time.Since(cached.fetchedAt) < l.cacheTTL {
+			l.lru.MoveToFront(elem)
+			l.mu.Unlock()
+			return cached.key, nil
+		}
+	}
+	l.mu.Unlock()
+
+	stored, err := l.repo.GetAPIKey(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		stored, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.lastSweep) >= l.cacheTTL {
+		l.sweep(now)
+	}
+	l.updateBucket(id, stored, now)
+	if stored == nil {
+		if elem, ok := l.keys[id]; ok {
+			l.evict(elem)
+		}
+		return nil, nil
+	}
+	if elem, ok := l.keys[id]; ok {
+		*elem.Value.(*cachedKey) = cachedKey{id: id, key: stored, fetchedAt: now}
+		l.lru.MoveToFront(elem)
+		return stored, nil
+	}
+	l.keys[id] = l.lru.PushFront(&cachedKey{id: id, key: stored, fetchedAt: now})
+	if l.lru.Len() > l.maxKeys {
+		l.evict(l.lru.Back())
+	}
+	return stored, nil
+}
+
+// evict removes a cache entry. The bucket of its key is left to sweep, once it has refilled.
+func (l *Limiter) evict(elem *list.Element) {
+	delete(l.keys, elem.Value.(*cachedKey).id)
+	l.lru.Remove(elem)
+}
+
+// updateBucket applies the current limits of a key fetched from the repository to its bucket,
+// keeping the tokens it holds, and drops the bucket of keys that no longer exist or are revoked.
+func (l *Limiter) updateBucket(id string, stored *repository.APIKey, now time.Time) {
+	if stored == nil || stored.RevokedAt != nil || stored.RequestsPerSecond <= 0 || stored.Burst < 1 {
+		delete(l.buckets, id)
+		return
+	}
+
+	limit := rate.Limit(stored.RequestsPerSecond * l.factor)
+	burst := max(1, int(float64(stored.Burst)*l.factor))
+	b := l.buckets[id]
+	if b == nil {
+		l.buckets[id] = &bucket{Limiter: rate.NewLimiter(limit, burst), requestsPerSecond: stored.RequestsPerSecond, burst: stored.Burst}
+		return
+	}
+	if b.requestsPerSecond != stored.RequestsPerSecond || b.burst != stored.Burst {
+		b.SetLimitAt(now, limit)
+		b.SetBurstAt(now, burst)
+		b.requestsPerSecond, b.burst = stored.RequestsPerSecond, stored.Burst
+	}
+}
+
+// sweep evicts expired cache entries and the buckets of keys that are no longer cached once they
+// have refilled, since a new bucket would then be identical.
+func (l *Limiter) sweep(now time.Time) {
+	l.lastSweep = now
+	for _, elem := range l.keys {
+		if now.Sub(elem.Value.(*cachedKey).fetchedAt) >= l.cacheTTL {
+			l.evict(elem)
+		}
+	}
+	for id, b := range l.buckets {
+		if _, ok := l.keys[id]; !ok && b.TokensAt(now) >= float64(b.Burst()) {
+			delete(l.buckets, id)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package apikey
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Middleware authenticates the X-API-Key of a request and limits its rate, answering 429 with
+// Retry-After once the key's bucket is empty. Requests without a key go through anonymously.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw := r.Header.Get(HeaderName)
+		if raw == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key, err := l.Authenticate(r.Context(), raw)
+		if err != nil {
+			if !errors.Is(err, ErrInvalid) {
+				slog.Error("failed to look up API key", "error", err)
+				writeError(w, http.StatusServiceUnavailable, "UNAVAILABLE", "failed to verify the API key")
+				return
+			}
+			writeError(w, http.StatusUnauthorized, "UNAUTHENTICATED", err.Error())
+			return
+		}
+
+		if ok, retryAfter := l.Allow(key); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(retrySeconds(retryAfter)))
+			writeError(w, http.StatusTooManyRequests, "RESOURCE_EXHAUSTED", "rate limit of the API key exceeded")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), key)))
+	})
+}
+
+// retrySeconds rounds a delay up to whole seconds, as Retry-After expects.
+func retrySeconds(delay time.Duration) int {
+	return int(math.Ceil(delay.Seconds()))
+}
+
+func writeError(w http.ResponseWriter, statusCode int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]any{
+		"errors": []map[string]any{{
+			"message":    message,
+			"extensions": map[string]any{"code": code},
+		}},
+	})
+}
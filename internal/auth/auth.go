@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/pkg"
+)
+
+// Roles known to the services. RoleAdmin is granted every role.
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+)
+
+// ErrNoToken is returned when a request carries no bearer token.
+var ErrNoToken = errors.New("no bearer token")
+
+// Claims identify the caller of a request.
+type Claims struct {
+	Subject string
+	Roles   []string
+	// token is the raw JWT, forwarded to the gRPC server so it can verify the claims itself.
+	token string
+}
+
+// HasRole reports whether the caller has role, or is an admin.
+func (c *Claims) HasRole(role string) bool {
+	return slices.Contains(c.Roles, role) || slices.Contains(c.Roles, RoleAdmin)
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying claims.
+func NewContext(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, contextKey{}, claims)
+}
+
+// FromContext returns the claims of the caller in ctx, if it authenticated.
+func FromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(contextKey{}).(*Claims)
+	return claims, ok
+}
+
+// Verifier validates tokens signed with HS256/384/512 or RS256/384/512 by a key of its KeySet.
+type Verifier struct {
+	keys       *KeySet
+	parser     *jwt.Parser
+	rolesClaim string
+}
+
+// VerifierOptions are the claims a token must carry; empty fields are not checked.
+type VerifierOptions struct {
+	Issuer     string
+	Audience   string
+	RolesClaim string // defaults to roles
+	Leeway     time.Duration
+}
+
+// NewVerifier returns a Verifier of tokens signed by keys.
+func NewVerifier(keys *KeySet, opts VerifierOptions) *Verifier {
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"HS256", "HS384", "HS512", "RS256", "RS384", "RS512"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(opts.Leeway),
+	}
+	if opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.Issuer))
+	}
+	if opts.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(opts.Audience))
+	}
+	rolesClaim := opts.RolesClaim
+	if rolesClaim == "" {
+		rolesClaim = "roles"
+	}
+	return &Verifier{keys: keys, parser: jwt.NewParser(parserOpts...), rolesClaim: rolesClaim}
+}
+
+// Verify validates a raw token and returns its claims.
+func (v *Verifier) Verify(raw string) (*Claims, error) {
+	var mapClaims jwt.MapClaims
+	if _, err := v.parser.ParseWithClaims(raw, &mapClaims, v.keys.keyfunc); err != nil {
+		return nil, err
+	}
+
+	subject, err := mapClaims.GetSubject()
+	if err != nil {
+		return nil, err
+	}
+	claims := &Claims{Subject: subject, token: raw}
+
+	// Roles may be a list or, as some providers issue them, a space separated string.
+	switch roles := mapClaims[v.rolesClaim].(type) {
+	case nil:
+	case string:
+		claims.Roles = strings.Fields(roles)
+	case []any:
+		for _, role := range roles {
+			name, ok := role.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid %s claim", v.rolesClaim)
+			}
+			claims.Roles = append(claims.Roles, name)
+		}
+	default:
+		return nil, fmt.Errorf("invalid %s claim", v.rolesClaim)
+	}
+	return claims, nil
+}
+
+// bearerToken returns the token of an Authorization header value.
+func bearerToken(header string) (string, error) {
+	if header == "" {
+		return "", ErrNoToken
+	}
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", errors.New("authorization must be a bearer token")
+	}
+	return strings.TrimSpace(token), nil
+}
+
+// defaultRefresh is how often the JWKS is reloaded when the configuration does not say.
+const defaultRefresh = 10 * time.Minute
+
+// Open returns a Verifier for cfg, reloading its keys in the background, or nil when cfg
+// configures no JWKS and authentication is disabled.
+func Open(ctx context.Context, cfg pkg.Auth) (*Verifier, error) {
+	if cfg.JWKS == "" {
+		return nil, nil
+	}
+
+	keys, err := NewKeySet(ctx, cfg.JWKS)
+	if err != nil {
+		return nil, err
+	}
+	refresh := cfg.Refresh
+	if refresh <= 0 {
+		refresh = defaultRefresh
+	}
+	go keys.Refresh(context.Background(), refresh)
+
+	return NewVerifier(keys, VerifierOptions{
+		Issuer:     cfg.Issuer,
+		Audience:   cfg.Audience,
+		RolesClaim: cfg.RolesClaim,
+		Leeway:     cfg.Leeway,
+	}), nil
+}
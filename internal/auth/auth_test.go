@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var hmacSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// testKeys writes a JWKS with an HMAC key "hs" and an RSA key "rs" and returns its key set
+// and the RSA private key.
+func testKeys(t *testing.T) (*KeySet, *rsa.PrivateKey) {
+	t.Helper()
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := map[string]any{"keys": []map[string]string{
+		{"kty": "oct", "kid": "hs", "k": base64.RawURLEncoding.EncodeToString(hmacSecret)},
+		{
+			"kty": "RSA", "kid": "rs", "alg": "RS256", "use": "sig",
+			"n": base64.RawURLEncoding.EncodeToString(private.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(private.E)).Bytes()),
+		},
+	}}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := NewKeySet(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys, private
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	raw, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func claimsWith(extra jwt.MapClaims) jwt.MapClaims {
+	claims := jwt.MapClaims{"sub": "user-1", "exp": time.Now().Add(time.Hour).Unix(), "iss": "https://issuer", "aud": "products"}
+	for k, v := range extra {
+		if v == nil {
+			delete(claims, k)
+		} else {
+			claims[k] = v
+		}
+	}
+	return claims
+}
+
+func TestHasRole(t *testing.T) {
+	tests := []struct {
+		roles []string
+		role  string
+		want  bool
+	}{
+		{[]string{RoleEditor}, RoleEditor, true},
+		{[]string{RoleEditor}, RoleAdmin, false},
+		{[]string{RoleAdmin}, RoleEditor, true},
+		{[]string{RoleAdmin}, RoleAdmin, true},
+		{nil, RoleEditor, false},
+		{[]string{"viewer"}, RoleEditor, false},
+	}
+	for _, tt := range tests {
+		claims := &Claims{Roles: tt.roles}
+		if got := claims.HasRole(tt.role); got != tt.want {
+			t.Errorf("%v.HasRole(%q) = %v, want %v", tt.roles, tt.role, got, tt.want)
+		}
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		header  string
+		want    string
+		wantErr bool
+	}{
+		{"Bearer abc", "abc", false},
+		{"bearer abc ", "abc", false},
+		{"Basic abc", "", true},
+		{"Bearer", "", true},
+		{"Bearer ", "", true},
+	}
+	for _, tt := range tests {
+		got, err := bearerToken(tt.header)
+		if (err != nil) != tt.wantErr || errors.Is(err, ErrNoToken) {
+			t.Errorf("bearerToken(%q) error = %v, wantErr %v", tt.header, err, tt.wantErr)
+		} else if got != tt.want {
+			t.Errorf("bearerToken(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+
+	if _, err := bearerToken(""); !errors.Is(err, ErrNoToken) {
+		t.Errorf("bearerToken(\"\") error = %v, want ErrNoToken", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	keys, private := testKeys(t)
+	verifier := NewVerifier(keys, VerifierOptions{Issuer: "https://issuer", Audience: "products"})
+
+	// An RSA public key used as an HMAC secret must not verify anything.
+	publicAsSecret := private.PublicKey.N.Bytes()
+
+	tests := []struct {
+		name      string
+		token     string
+		wantRoles []string
+		wantErr   bool
+	}{
+		{"HS256 with roles list", sign(t, jwt.SigningMethodHS256, "hs", hmacSecret, claimsWith(jwt.MapClaims{"roles": []string{"editor", "admin"}})), []string{"editor", "admin"}, false},
+		{"HS512 with roles string", sign(t, jwt.SigningMethodHS512, "hs", hmacSecret, claimsWith(jwt.MapClaims{"roles": "editor admin"})), []string{"editor", "admin"}, false},
+		{"RS256 without roles", sign(t, jwt.SigningMethodRS256, "rs", private, claimsWith(nil)), nil, false},
+		{"RS384 on an RS256 key", sign(t, jwt.SigningMethodRS384, "rs", private, claimsWith(nil)), nil, true},
+		{"HS256 on an RSA key", sign(t, jwt.SigningMethodHS256, "rs", publicAsSecret, claimsWith(nil)), nil, true},
+		{"wrong secret", sign(t, jwt.SigningMethodHS256, "hs", []byte("wrong"), claimsWith(nil)), nil, true},
+		{"unknown kid", sign(t, jwt.SigningMethodHS256, "other", hmacSecret, claimsWith(nil)), nil, true},
+		{"no kid with several keys", sign(t, jwt.SigningMethodHS256, "", hmacSecret, claimsWith(nil)), nil, true},
+		{"none", sign(t, jwt.SigningMethodNone, "hs", jwt.UnsafeAllowNoneSignatureType, claimsWith(nil)), nil, true},
+		{"expired", sign(t, jwt.SigningMethodHS256, "hs", hmacSecret, claimsWith(jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()})), nil, true},
+		{"no expiry", sign(t, jwt.SigningMethodHS256, "hs", hmacSecret, claimsWith(jwt.MapClaims{"exp": nil})), nil, true},
+		{"wrong issuer", sign(t, jwt.SigningMethodHS256, "hs", hmacSecret, claimsWith(jwt.MapClaims{"iss": "https://other"})), nil, true},
+		{"wrong audience", sign(t, jwt.SigningMethodHS256, "hs", hmacSecret, claimsWith(jwt.MapClaims{"aud": "other"})), nil, true},
+		{"invalid roles", sign(t, jwt.SigningMethodHS256, "hs", hmacSecret, claimsWith(jwt.MapClaims{"roles": []any{"editor", 1}})), nil, true},
+		{"garbage", "not.a.token", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := verifier.Verify(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if claims.Subject != "user-1" || !slices.Equal(claims.Roles, tt.wantRoles) {
+				t.Errorf("Verify() = %+v, want subject user-1 and roles %v", claims, tt.wantRoles)
+			}
+		})
+	}
+}
+
+func TestVerifyRolesClaim(t *testing.T) {
+	keys, _ := testKeys(t)
+	verifier := NewVerifier(keys, VerifierOptions{RolesClaim: "scope"})
+	claims, err := verifier.Verify(sign(t, jwt.SigningMethodHS256, "hs", hmacSecret, claimsWith(jwt.MapClaims{"scope": "admin", "roles": "editor"})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(claims.Roles, []string{"admin"}) {
+		t.Errorf("roles = %v, want the scope claim [admin]", claims.Roles)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	keys, _ := testKeys(t)
+	verifier := NewVerifier(keys, VerifierOptions{})
+	roles := map[string]string{"/admin": RoleAdmin, "/write": RoleEditor}
+	token := func(roles ...string) string {
+		return "Bearer " + sign(t, jwt.SigningMethodHS256, "hs", hmacSecret, claimsWith(jwt.MapClaims{"roles": roles}))
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		header string
+		want   codes.Code
+	}{
+		{"anonymous read", "/read", "", codes.OK},
+		{"anonymous write", "/write", "", codes.Unauthenticated},
+		{"invalid token on a read", "/read", "Bearer nope", codes.Unauthenticated},
+		{"malformed header", "/read", "Basic abc", codes.Unauthenticated},
+		{"editor write", "/write", token(RoleEditor), codes.OK},
+		{"editor admin", "/admin", token(RoleEditor), codes.PermissionDenied},
+		{"admin write", "/write", token(RoleAdmin), codes.OK},
+		{"no roles write", "/write", token(), codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.header != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(MetadataKey, tt.header))
+			}
+			ctx, err := verifier.authenticate(ctx, tt.method, roles)
+			if got := status.Code(err); got != tt.want {
+				t.Fatalf("authenticate() code = %s, want %s (%v)", got, tt.want, err)
+			}
+			if err != nil || tt.header == "" {
+				return
+			}
+			if _, ok := FromContext(ctx); !ok {
+				t.Error("authenticate() did not store the claims in the context")
+			}
+		})
+	}
+}
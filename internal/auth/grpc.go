@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// MetadataKey is the gRPC metadata key carrying the caller's token between services.
+// The claims travel as the signed token, so the server does not have to trust the gateway.
+const MetadataKey = "authorization"
+
+// UnaryClientInterceptor forwards the token of the claims in the context to the server.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(outgoing(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor is the streaming counterpart of UnaryClientInterceptor.
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(outgoing(ctx), desc, cc, method, opts...)
+	}
+}
+
+func outgoing(ctx context.Context) context.Context {
+	if claims, ok := FromContext(ctx); ok {
+		return metadata.AppendToOutgoingContext(ctx, MetadataKey, "Bearer "+claims.token)
+	}
+	return ctx
+}
+
+// UnaryServerInterceptor verifies the token in the incoming metadata and stores its claims in
+// the context. Methods listed in roles require the caller to have the given role.
+func (v *Verifier) UnaryServerInterceptor(roles map[string]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := v.authenticate(ctx, info.FullMethod, roles)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of UnaryServerInterceptor.
+func (v *Verifier) StreamServerInterceptor(roles map[string]string) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := v.authenticate(ss.Context(), info.FullMethod, roles)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func (v *Verifier) authenticate(ctx context.Context, method string, roles map[string]string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var header string
+	if values := md.Get(MetadataKey); len(values) > 0 {
+		header = values[0]
+	}
+
+	raw, err := bearerToken(header)
+	var claims *Claims
+	if err == nil {
+		if claims, err = v.Verify(raw); err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "invalid token: %v", err)
+		}
+		ctx = NewContext(ctx, claims)
+	} else if !errors.Is(err, ErrNoToken) {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	role, restricted := roles[method]
+	if !restricted {
+		return ctx, nil
+	}
+	if claims == nil {
+		return nil, status.Errorf(codes.Unauthenticated, "%s requires authentication", method)
+	}
+	if !claims.HasRole(role) {
+		return nil, status.Errorf(codes.PermissionDenied, "%s requires the %s role", method, role)
+	}
+	return ctx, nil
+}
+
+// serverStream overrides the context of a grpc.ServerStream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,170 @@
+// Package auth validates the JWTs callers present, on the gateway and on the gRPC server,
+// and carries their claims through request contexts.
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwks is a JSON Web Key Set (RFC 7517). Symmetric keys are "oct" keys with the secret in k.
+type jwks struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Alg string `json:"alg"`
+		Use string `json:"use"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+		K   string `json:"k"`
+	} `json:"keys"`
+}
+
+// key is a verification key and the family of algorithms it may verify.
+type key struct {
+	alg    string // optional, restricts the key to a single algorithm
+	hmac   []byte
+	public *rsa.PublicKey
+}
+
+// KeySet holds the keys tokens are verified with, loaded from a JWKS file or URL.
+type KeySet struct {
+	source string
+	client *http.Client
+	keys   atomic.Pointer[map[string]key] // by kid
+}
+
+// NewKeySet loads the JWKS at source, a file path or an http(s) URL.
+func NewKeySet(ctx context.Context, source string) (*KeySet, error) {
+	s := &KeySet{source: source, client: &http.Client{Timeout: 10 * time.Second}}
+	if err := s.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload fetches the keys again; on error the current keys are kept.
+func (s *KeySet) Reload(ctx context.Context) error {
+	data, err := s.read(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read JWKS: %w", err)
+	}
+
+	var set jwks
+	if err := json.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("invalid JWKS: %w", err)
+	}
+
+	keys := make(map[string]key, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		parsed := key{alg: k.Alg}
+		switch k.Kty {
+		case "oct":
+			if parsed.hmac, err = base64.RawURLEncoding.DecodeString(k.K); err != nil || len(parsed.hmac) == 0 {
+				return fmt.Errorf("invalid oct key %q", k.Kid)
+			}
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil || len(n) == 0 || len(e) == 0 {
+				return fmt.Errorf("invalid RSA key %q", k.Kid)
+			}
+			parsed.public = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		default:
+			continue
+		}
+		keys[k.Kid] = parsed
+	}
+	if len(keys) == 0 {
+		return errors.New("JWKS has no HMAC or RSA signing keys")
+	}
+
+	s.keys.Store(&keys)
+	return nil
+}
+
+// Refresh reloads the keys every interval until ctx is done, so rotated keys are picked up.
+func (s *KeySet) Refresh(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Reload(ctx); err != nil {
+				slog.Warn("failed to refresh JWKS, keeping the previous keys", "source", s.source, "error", err)
+			}
+		}
+	}
+}
+
+func (s *KeySet) read(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(s.source, "http://") && !strings.HasPrefix(s.source, "https://") {
+		return os.ReadFile(s.source)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// keyfunc returns the key of the token's kid, checking that it fits the token's algorithm so
+// that an RSA public key can never be used as an HMAC secret.
+func (s *KeySet) keyfunc(token *jwt.Token) (any, error) {
+	keys := *s.keys.Load()
+
+	kid, _ := token.Header["kid"].(string)
+	k, ok := keys[kid]
+	if !ok && kid == "" && len(keys) == 1 {
+		for _, only := range keys {
+			k, ok = only, true
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	alg := token.Method.Alg()
+	if k.alg != "" && k.alg != alg {
+		return nil, fmt.Errorf("key %q does not verify %s", kid, alg)
+	}
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if k.hmac != nil {
+			return k.hmac, nil
+		}
+	case *jwt.SigningMethodRSA:
+		if k.public != nil {
+			return k.public, nil
+		}
+	}
+	return nil, fmt.Errorf("key %q does not verify %s", kid, alg)
+}
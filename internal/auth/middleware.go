@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+)
+
+// Middleware stores the claims of the bearer token of a request in its context. Requests
+// without a token go through anonymously, those with an invalid one are rejected with 401.
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, err := bearerToken(r.Header.Get("Authorization"))
+		if errors.Is(err, ErrNoToken) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		var claims *Claims
+		if err == nil {
+			claims, err = v.Verify(raw)
+		}
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]any{
+				"errors": []map[string]any{{
+					"message":    "invalid token: " + err.Error(),
+					"extensions": map[string]any{"code": "UNAUTHENTICATED"},
+				}},
+			})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), claims)))
+	})
+}
+
+// WebsocketInit authenticates subscriptions with the Authorization value of the connection_init
+// payload, since browsers cannot set headers on websockets.
+func (v *Verifier) WebsocketInit(ctx context.Context, payload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+	raw, err := bearerToken(payload.Authorization())
+	if errors.Is(err, ErrNoToken) {
+		return ctx, &payload, nil
+	}
+	if err != nil {
+		return ctx, nil, err
+	}
+	claims, err := v.Verify(raw)
+	if err != nil {
+		return ctx, nil, err
+	}
+	return NewContext(ctx, claims), &payload, nil
+}
@@ -0,0 +1,162 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/pkg"
+)
+
+// ServerConfig returns the TLS configuration of a server, or nil when cfg disables TLS. With a
+// ca_file, clients must present a certificate it signed (mTLS) and, with allowed_sans, one of
+// whose SANs is listed. Files are reloaded on change until ctx is done.
+func ServerConfig(ctx context.Context, cfg pkg.TLS) (*tls.Config, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if cfg.CertFile == "" {
+		return nil, errors.New("a server needs cert_file and key_file")
+	}
+	minVersion, err := version(cfg.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := newReloader(ctx, cfg.CertFile, cfg.KeyFile, cfg.CAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		MinVersion: minVersion,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return r.current.Load().cert, nil
+		},
+	}
+	if cfg.CAFile != "" {
+		// Client certificates are verified against the current CA pool rather than a fixed
+		// ClientCAs, so that a rotated CA is trusted without restarting.
+		config.ClientAuth = tls.RequireAnyClientCert
+		config.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifyClient(rawCerts, r.current.Load().pool, cfg.AllowedSANs)
+		}
+	}
+	return config, nil
+}
+
+func verifyClient(rawCerts [][]byte, roots *x509.CertPool, allowedSANs []string) error {
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("invalid client certificate: %w", err)
+		}
+		certs[i] = cert
+	}
+	if len(certs) == 0 {
+		return errors.New("client presented no certificate")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return err
+	}
+	return checkSANs(certs[0], allowedSANs)
+}
+
+// ClientConfig returns the TLS configuration of a client, or nil when cfg disables TLS. The
+// server certificate is verified against ca_file, or the system roots without one, for
+// server_name or the dialed host, and must carry one of allowed_sans when set. cert_file and
+// key_file are presented to servers requiring mTLS. Files are reloaded on change until ctx is done.
+func ClientConfig(ctx context.Context, cfg pkg.TLS) (*tls.Config, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	minVersion, err := version(cfg.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := newReloader(ctx, cfg.CertFile, cfg.KeyFile, cfg.CAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		MinVersion: minVersion,
+		ServerName: cfg.ServerName,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			if m := r.current.Load(); m.cert != nil {
+				return m.cert, nil
+			}
+			return &tls.Certificate{}, nil
+		},
+		// The chain is verified in VerifyConnection against the current CA pool instead, so
+		// that a rotated CA is trusted without restarting.
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			return verifyServer(cs, r.current.Load().pool, cfg.AllowedSANs)
+		},
+	}, nil
+}
+
+func verifyServer(cs tls.ConnectionState, roots *x509.CertPool, allowedSANs []string) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("server presented no certificate")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       cs.ServerName,
+		Roots:         roots,
+		Intermediates: intermediates,
+	}); err != nil {
+		return err
+	}
+	return checkSANs(cs.PeerCertificates[0], allowedSANs)
+}
+
+// checkSANs returns an error unless cert carries one of allowed as a DNS, IP or URI SAN;
+// an empty allowed accepts any certificate.
+func checkSANs(cert *x509.Certificate, allowed []string) error {
+	if len(allowed) == 0 {
+		return nil
+	}
+	sans := slices.Clone(cert.DNSNames)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	for _, san := range sans {
+		if slices.Contains(allowed, san) {
+			return nil
+		}
+	}
+	return fmt.Errorf("certificate of %q has none of the allowed SANs", cert.Subject.CommonName)
+}
+
+func version(name string) (uint16, error) {
+	switch name {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS version %q, expected 1.2 or 1.3", name)
+	}
+}
@@ -0,0 +1,125 @@
+// Package certs builds TLS configurations for the gRPC server, the gateway's connection to it
+// and the GraphQL listener, reloading certificates and CAs when their files change.
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay groups the bursts of events a certificate rotation produces into one reload.
+const reloadDelay = 500 * time.Millisecond
+
+// material is what is read from the files of a configuration.
+type material struct {
+	cert *tls.Certificate // nil without a cert_file
+	pool *x509.CertPool   // nil without a ca_file
+}
+
+// reloader holds the current material of a set of files and reloads it when they change.
+type reloader struct {
+	certFile, keyFile, caFile string
+	current                   atomic.Pointer[material]
+}
+
+func newReloader(ctx context.Context, certFile, keyFile, caFile string) (*reloader, error) {
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("cert_file and key_file must be set together")
+	}
+
+	r := &reloader{certFile: certFile, keyFile: keyFile, caFile: caFile}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	if certFile != "" || caFile != "" {
+		go func() {
+			if err := r.watch(ctx); err != nil {
+				slog.Error("failed to watch certificates, they will not be reloaded", "error", err)
+			}
+		}()
+	}
+	return r, nil
+}
+
+func (r *reloader) reload() error {
+	var m material
+	if r.certFile != "" {
+		cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load certificate: %w", err)
+		}
+		m.cert = &cert
+	}
+	if r.caFile != "" {
+		pem, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return fmt.Errorf("failed to read CA: %w", err)
+		}
+		m.pool = x509.NewCertPool()
+		if !m.pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates in CA file %s", r.caFile)
+		}
+	}
+	r.current.Store(&m)
+	return nil
+}
+
+// watch reloads the files whenever their directories change, until ctx is done. Directories
+// are watched rather than files, which Kubernetes and cert-manager replace on rotation.
+func (r *reloader) watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	dirs := make(map[string]bool)
+	for _, file := range []string{r.certFile, r.keyFile, r.caFile} {
+		if file != "" {
+			dirs[filepath.Dir(file)] = true
+		}
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			return err
+		}
+	}
+
+	reload := time.NewTimer(0)
+	<-reload.C
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Has(fsnotify.Chmod) {
+				continue
+			}
+			reload.Reset(reloadDelay)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Warn("certificate watcher error", "error", err)
+		case <-reload.C:
+			if err := r.reload(); err != nil {
+				slog.Error("failed to reload certificates, keeping the previous ones", "cert", r.certFile, "error", err)
+				continue
+			}
+			slog.Info("reloaded certificates", "cert", r.certFile, "ca", r.caFile)
+		}
+	}
+}
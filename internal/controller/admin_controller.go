@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/apikey"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/repository"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// KeyDefaults are the limits of keys issued without explicit ones.
+type KeyDefaults struct {
+	RequestsPerSecond float64
+	Burst             int32
+}
+
+type AdminController struct {
+	pb.UnimplementedAdminServiceServer
+	repo     repository.Repository
+	defaults KeyDefaults
+}
+
+func NewAdminController(repo repository.Repository, defaults KeyDefaults) *AdminController {
+	return &AdminController{repo: repo, defaults: defaults}
+}
+
+func (c *AdminController) IssueAPIKey(ctx context.Context, req *pb.IssueAPIKeyRequest) (*pb.IssueAPIKeyResponse, error) {
+	var v violations
+	if strings.TrimSpace(req.Name) == "" {
+		v.add("name", reasonRequired, "name is required")
+	}
+	if req.RequestsPerSecond < 0 {
+		v.add("requests_per_second", reasonOutOfRange, "requests_per_second must not be negative")
+	}
+	if req.Burst < 0 {
+		v.add("burst", reasonOutOfRange, "burst must not be negative")
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	id, key, secretHash, err := apikey.Generate()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to generate API key")
+	}
+
+	stored := &repository.APIKey{
+		ID:                id,
+		SecretHash:        secretHash,
+		Name:              req.Name,
+		RequestsPerSecond: req.RequestsPerSecond,
+		Burst:             req.Burst,
+		CreatedAt:         time.Now(),
+	}
+	if stored.RequestsPerSecond == 0 {
+		stored.RequestsPerSecond = c.defaults.RequestsPerSecond
+	}
+	if stored.Burst == 0 {
+		stored.Burst = c.defaults.Burst
+	}
+	// A bucket without a positive rate and burst would reject every request of the key.
+	if stored.RequestsPerSecond <= 0 || stored.Burst < 1 {
+		return nil, status.Errorf(codes.FailedPrecondition, "no default API key limits are configured, set requests_per_second and burst")
+	}
+
+	if err := c.repo.CreateAPIKey(ctx, stored); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to store API key: %v", err)
+	}
+
+	return &pb.IssueAPIKeyResponse{
+		Id:                stored.ID,
+		Key:               key,
+		RequestsPerSecond: stored.RequestsPerSecond,
+		Burst:             stored.Burst,
+		CreatedAt:         timestamppb.New(stored.CreatedAt),
+	}, nil
+}
+
+func (c *AdminController) RevokeAPIKey(ctx context.Context, req *pb.RevokeAPIKeyRequest) (*pb.RevokeAPIKeyResponse, error) {
+	if req.Id == "" {
+		var v violations
+		v.add("id", reasonRequired, "id is required")
+		return nil, v.err()
+	}
+
+	stored, err := c.repo.GetAPIKey(ctx, req.Id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, notFound("api_key", req.Id)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get API key: %v", err)
+	}
+	if stored.RevokedAt != nil {
+		return &pb.RevokeAPIKeyResponse{RevokedAt: timestamppb.New(*stored.RevokedAt)}, nil
+	}
+
+	revokedAt := time.Now()
+	if err := c.repo.RevokeAPIKey(ctx, req.Id, revokedAt); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, notFound("api_key", req.Id)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to revoke API key: %v", err)
+	}
+	return &pb.RevokeAPIKeyResponse{RevokedAt: timestamppb.New(revokedAt)}, nil
+}
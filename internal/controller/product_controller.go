@@ -3,11 +3,18 @@ package controller
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"strconv"
 	"time"
 
-	"github.com/gocql/gocql"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/cursor"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/events"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/repository"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/requestid"
 	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/snowflake"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/tenant"
 	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/pb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -15,16 +22,18 @@ import (
 
 type ProductController struct {
 	pb.UnimplementedProductServiceServer
-	session *gocql.Session
+	repo    repository.Repository
+	watcher events.Watcher
+	cursors *cursor.Codec
 }
 
-func NewProductController(session *gocql.Session) *ProductController {
-	return &ProductController{session: session}
+func NewProductController(repo repository.Repository, watcher events.Watcher, cursors *cursor.Codec) *ProductController {
+	return &ProductController{repo: repo, watcher: watcher, cursors: cursors}
 }
 
 func (c *ProductController) CreateCategory(ctx context.Context, req *pb.CreateCategoryRequest) (*pb.CreateCategoryResponse, error) {
-	if req.Name == "" || req.Description == "" {
-		return nil, status.Errorf(codes.InvalidArgument, "name and description are required")
+	if err := validateCreateCategory(req).err(); err != nil {
+		return nil, err
 	}
 
 	categoryId, err := snowflake.GenerateID()
@@ -32,24 +41,36 @@ func (c *ProductController) CreateCategory(ctx context.Context, req *pb.CreateCa
 		return nil, status.Errorf(codes.Internal, "failed to generate category id")
 	}
 
-	now := time.Now()
-	createCategoryQuery := `INSERT INTO products_keyspace_v2.categories(id, name, description, created_at) VALUES(?, ?, ?, ?)`
+	category := &repository.Category{
+		ID:          int64(categoryId),
+		Name:        req.Name,
+		Description: req.Description,
+		CreatedAt:   time.Now(),
+	}
 
-	if err := c.session.Query(createCategoryQuery, categoryId, req.Name, req.Description, now).WithContext(ctx).Exec(); err != nil {
+	if err := c.repo.CreateCategory(ctx, category); err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to create category: %v", err)
 	}
 
 	return &pb.CreateCategoryResponse{
-		Id:          int64(categoryId),
-		Name:        req.Name,
-		Description: req.Description,
-		CreatedAt:   timestamppb.New(now),
+		Id:          category.ID,
+		Name:        category.Name,
+		Description: category.Description,
+		CreatedAt:   timestamppb.New(category.CreatedAt),
 	}, nil
 }
 
 func (c *ProductController) CreateProduct(ctx context.Context, req *pb.CreateProductRequest) (*pb.CreateProductResponse, error) {
-	if req.Name == "" || req.Description == "" || req.Price == 0 || req.CategoryId == 0 || req.Stock == 0 {
-		return nil, status.Errorf(codes.InvalidArgument, "name, description, price, stock, and category id are required")
+	if err := validateCreateProduct(req).err(); err != nil {
+		return nil, err
+	}
+	if _, err := c.repo.GetCategory(ctx, req.CategoryId); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			var v violations
+			v.add("category_id", reasonNotFound, "category does not exist")
+			return nil, v.err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get category: %v", err)
 	}
 
 	productId, err := snowflake.GenerateID()
@@ -58,47 +79,78 @@ func (c *ProductController) CreateProduct(ctx context.Context, req *pb.CreatePro
 	}
 
 	now := time.Now()
-	outboxID := gocql.TimeUUID()
-	bucket := now.Format("2006-01-02")
-	eventType := "create_product_event"
 
-	product := &pb.Product{
-		Id:          int64(productId),
-		CategoryId:  req.CategoryId,
+	product := &repository.Product{
+		ID:          int64(productId),
+		CategoryID:  req.CategoryId,
 		Name:        req.Name,
 		Description: req.Description,
-		Price:       req.Price,
+		Price:       repository.Money{Units: req.Price.Units, Nanos: req.Price.Nanos, Currency: req.Price.CurrencyCode},
 		Stock:       req.Stock,
-		CreatedAt:   timestamppb.New(now),
-		UpdatedAt:   timestamppb.New(now),
+		CreatedAt:   now,
+		UpdatedAt:   now,
 	}
+	pbProduct := toProto(product)
 
-	payload, err := json.Marshal(product)
+	payload, err := json.Marshal(pbProduct)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to marshal product: %v", err)
 	}
 
-	batch := c.session.NewBatch(gocql.LoggedBatch).WithContext(ctx)
+	event := &repository.OutboxEvent{EventType: events.ProductCreated, Payload: payload}
+	event.RequestID, _ = requestid.FromContext(ctx)
+	if err := c.repo.CreateProduct(ctx, product, event); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create product: %v", err)
+	}
 
-	batch.Query(
-		`INSERT INTO products_keyspace_v2.products 
-		(id, name, description, price, stock, category_id, created_at, updated_at) 
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
-		productId, req.Name, req.Description, req.Price, req.Stock, req.CategoryId, now, now,
-	)
+	return &pb.CreateProductResponse{Product: pbProduct}, nil
+}
 
-	batch.Query(
-		`INSERT INTO products_keyspace_v2.products_outbox 
-		(id, bucket, payload, event_type) 
-		VALUES (?, ?, ?, ?)`,
-		outboxID, bucket, payload, eventType,
-	)
+// UpdateProduct applies the fields set in the request to the stored product and records an
+// update event. Concurrent updates of the same product are last-write-wins.
+func (c *ProductController) UpdateProduct(ctx context.Context, req *pb.UpdateProductRequest) (*pb.UpdateProductResponse, error) {
+	if err := validateUpdateProduct(req).err(); err != nil {
+		return nil, err
+	}
 
-	if err := c.session.ExecuteBatch(batch); err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to create product: %v", err)
+	product, err := c.repo.GetProduct(ctx, req.CategoryId, req.ProductId)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, notFound("product", strconv.FormatInt(req.ProductId, 10))
+		}
+		return nil, status.Errorf(codes.Internal, "failed to fetch product: %v", err)
 	}
 
-	return &pb.CreateProductResponse{Product: product}, nil
+	if req.Name != nil {
+		product.Name = *req.Name
+	}
+	if req.Description != nil {
+		product.Description = *req.Description
+	}
+	if req.Price != nil {
+		product.Price = repository.Money{Units: req.Price.Units, Nanos: req.Price.Nanos, Currency: req.Price.CurrencyCode}
+	}
+	if req.Stock != nil {
+		product.Stock = *req.Stock
+	}
+	product.UpdatedAt = time.Now()
+	pbProduct := toProto(product)
+
+	payload, err := json.Marshal(pbProduct)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to marshal product: %v", err)
+	}
+
+	event := &repository.OutboxEvent{EventType: events.ProductUpdated, Payload: payload}
+	event.RequestID, _ = requestid.FromContext(ctx)
+	if err := c.repo.UpdateProduct(ctx, product, event); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, notFound("product", strconv.FormatInt(req.ProductId, 10))
+		}
+		return nil, status.Errorf(codes.Internal, "failed to update product: %v", err)
+	}
+
+	return &pb.UpdateProductResponse{Product: pbProduct}, nil
 }
 
 func (c *ProductController) GetCategory(ctx context.Context, req *pb.GetCategoryRequest) (*pb.GetCategoryResponse, error) {
@@ -106,103 +158,208 @@ func (c *ProductController) GetCategory(ctx context.Context, req *pb.GetCategory
 		return nil, status.Errorf(codes.InvalidArgument, "id is required")
 	}
 
-	getCategoryQuery := `SELECT id, name, description, created_at FROM products_keyspace_v2.categories WHERE id = ?`
-	var categoryId int64
-	var name, description string
-	var createdAt time.Time
-
-	if err := c.session.Query(getCategoryQuery, req.Id).WithContext(ctx).Scan(&categoryId, &name, &description, &createdAt); err != nil {
-		if err == gocql.ErrNotFound {
-			return nil, status.Errorf(codes.NotFound, "category not found")
+	category, err := c.repo.GetCategory(ctx, req.Id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, notFound("category", strconv.FormatInt(req.Id, 10))
 		}
 		return nil, status.Errorf(codes.Internal, "failed to get category: %v", err)
 	}
 
 	return &pb.GetCategoryResponse{
-		Id:          categoryId,
-		Name:        name,
-		Description: description,
-		CreatedAt:   timestamppb.New(createdAt),
+		Id:          category.ID,
+		Name:        category.Name,
+		Description: category.Description,
+		CreatedAt:   timestamppb.New(category.CreatedAt),
 	}, nil
 }
 
+// maxBatchGet bounds the ids of BatchGetCategories and BatchGetProducts requests.
+const maxBatchGet = 100
+
+// batchIDs validates the ids of a batch request and removes duplicates.
+func batchIDs(requested []int64) ([]int64, error) {
+	if len(requested) > maxBatchGet {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d ids can be requested at once", maxBatchGet)
+	}
+
+	seen := make(map[int64]bool, len(requested))
+	ids := make([]int64, 0, len(requested))
+	for _, id := range requested {
+		if id == 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "ids must not be 0")
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (c *ProductController) BatchGetCategories(ctx context.Context, req *pb.BatchGetCategoriesRequest) (*pb.BatchGetCategoriesResponse, error) {
+	ids, err := batchIDs(req.Ids)
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := c.repo.GetCategories(ctx, ids)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get categories: %v", err)
+	}
+
+	resp := &pb.BatchGetCategoriesResponse{Categories: make([]*pb.Category, len(categories))}
+	for i, category := range categories {
+		resp.Categories[i] = &pb.Category{
+			Id:          category.ID,
+			Name:        category.Name,
+			Description: category.Description,
+			CreatedAt:   timestamppb.New(category.CreatedAt),
+		}
+	}
+	return resp, nil
+}
+
+func (c *ProductController) BatchGetProducts(ctx context.Context, req *pb.BatchGetProductsRequest) (*pb.BatchGetProductsResponse, error) {
+	ids, err := batchIDs(req.Ids)
+	if err != nil {
+		return nil, err
+	}
+
+	products, err := c.repo.GetProducts(ctx, ids)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get products: %v", err)
+	}
+
+	resp := &pb.BatchGetProductsResponse{Products: make([]*pb.Product, len(products))}
+	for i, product := range products {
+		resp.Products[i] = toProto(product)
+	}
+	return resp, nil
+}
+
 func (c *ProductController) GetProduct(ctx context.Context, req *pb.GetProductRequest) (*pb.GetProductResponse, error) {
 	if req.CategoryId == 0 || req.ProductId == 0 {
 		return nil, status.Errorf(codes.InvalidArgument, "category id and product id are required")
 	}
 
-	var product pb.Product
-	var createdAt, updatedAt time.Time
-
-	getProductQuery := `SELECT id, name, description, price, stock, category_id, created_at, updated_at FROM products_keyspace_v2.products WHERE category_id = ? AND id = ?`
-	err := c.session.Query(getProductQuery, req.CategoryId, req.ProductId).WithContext(ctx).Scan(
-		&product.Id, &product.Name, &product.Description, &product.Price, &product.Stock, &product.CategoryId, &createdAt, &updatedAt,
-	)
+	product, err := c.repo.GetProduct(ctx, req.CategoryId, req.ProductId)
 	if err != nil {
-		if err == gocql.ErrNotFound {
-			return nil, status.Errorf(codes.NotFound, "product not found")
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, notFound("product", strconv.FormatInt(req.ProductId, 10))
 		}
 		return nil, status.Errorf(codes.Internal, "failed to fetch product: %v", err)
 	}
 
-	product.CreatedAt = timestamppb.New(createdAt)
-	product.UpdatedAt = timestamppb.New(updatedAt)
-
-	return &pb.GetProductResponse{Product: &product}, nil
+	return &pb.GetProductResponse{Product: toProto(product)}, nil
 }
 
+// ListProducts pages through a category. Pages are resumed with the signed cursors handed out
+// in the previous response; raw paging states are never accepted from clients.
 func (c *ProductController) ListProducts(ctx context.Context, req *pb.ListProductsRequest) (*pb.ListProductsResponse, error) {
 	if req.CategoryId == 0 || req.PageSize <= 0 {
 		return nil, status.Errorf(codes.InvalidArgument, "category id and page size are required")
 	}
+	if len(req.PagingState) > 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "paging_state is no longer accepted, use page_token")
+	}
 
-	query := c.session.Query(`
-		SELECT id, name, description, price, stock, created_at, updated_at 
-		FROM products_keyspace_v2.products 
-		WHERE category_id = ?`,
-		req.CategoryId,
-	).WithContext(ctx).PageSize(int(req.PageSize)).PageState(req.PagingState)
+	var position cursor.Cursor
+	if req.PageToken != "" {
+		var err error
+		if position, err = c.cursors.Decode(req.PageToken); err == nil {
+			err = position.Check(req.CategoryId, req.PageSize)
+		}
+		if err != nil {
+			var v violations
+			v.add("page_token", reasonInvalid, "invalid page token: "+err.Error())
+			return nil, v.err()
+		}
+	}
 
-	if len(req.PagingState) > 0 {
-		query = query.PageState(req.PagingState)
-	}
-
-	iter := query.Iter()
-	defer iter.Close()
-
-	var products []*pb.Product
-	var (
-		id          int64
-		name        string
-		description string
-		price       float32
-		stock       int32
-		createdAt   time.Time
-		updatedAt   time.Time
-	)
-
-	for iter.Scan(&id, &name, &description, &price, &stock, &createdAt, &updatedAt) {
-		products = append(products, &pb.Product{
-			Id:          id,
-			Name:        name,
-			Description: description,
-			Price:       price,
-			Stock:       stock,
-			CategoryId:  req.CategoryId,
-			CreatedAt:   timestamppb.New(createdAt),
-			UpdatedAt:   timestamppb.New(updatedAt),
-		})
-	}
-
-	// ✅ Check if Cassandra actually returns a paging state
-	nextPageState := iter.PageState()
-
-	if err := iter.Close(); err != nil {
+	page, err := c.repo.ListProducts(ctx, req.CategoryId, position.AfterID, int(req.PageSize))
+	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to list products: %v", err)
 	}
 
-	return &pb.ListProductsResponse{
-		Products:    products,
-		PagingState: nextPageState,
-	}, nil
+	resp := &pb.ListProductsResponse{
+		Products: make([]*pb.Product, len(page.Products)),
+		Cursors:  make([]string, len(page.Products)),
+	}
+	for i, product := range page.Products {
+		resp.Products[i] = toProto(product)
+		resp.Cursors[i] = c.cursors.Encode(cursor.Cursor{CategoryID: req.CategoryId, PageSize: req.PageSize, AfterID: product.ID})
+	}
+	if page.HasMore && len(resp.Cursors) > 0 {
+		resp.NextPageToken = resp.Cursors[len(resp.Cursors)-1]
+	}
+	return resp, nil
+}
+
+func (c *ProductController) CountProducts(ctx context.Context, req *pb.CountProductsRequest) (*pb.CountProductsResponse, error) {
+	if req.CategoryId == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "category id is required")
+	}
+
+	count, err := c.repo.CountProducts(ctx, req.CategoryId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to count products: %v", err)
+	}
+	return &pb.CountProductsResponse{Count: count}, nil
+}
+
+// WatchProducts streams the product events of the caller's tenant, optionally restricted to a category.
+// Each stream reads the event topic on its own, and sends are synchronous, so a slow receiver
+// is throttled by gRPC flow control without affecting other watchers.
+func (c *ProductController) WatchProducts(req *pb.WatchProductsRequest, stream pb.ProductService_WatchProductsServer) error {
+	ctx := stream.Context()
+	tenantID, _ := tenant.FromContext(ctx)
+
+	err := c.watcher.Watch(ctx, req.Cursor, func(event *pb.ProductEvent, eventTenant string) error {
+		if eventTenant != tenantID {
+			return nil
+		}
+		if req.CategoryId != 0 && event.Product.CategoryId != req.CategoryId {
+			return nil
+		}
+		return stream.Send(event)
+	})
+
+	switch {
+	case errors.Is(err, events.ErrInvalidCursor):
+		return status.Errorf(codes.InvalidArgument, "invalid cursor")
+	case ctx.Err() != nil:
+		return status.FromContextError(ctx.Err()).Err()
+	case err != nil:
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		return status.Errorf(codes.Unavailable, "failed to watch products: %v", err)
+	}
+	return nil
+}
+
+// notFound returns a NotFound status naming the missing resource in a ResourceInfo detail.
+func notFound(resourceType, name string) error {
+	st := status.Newf(codes.NotFound, "%s not found", resourceType)
+	if detailed, err := st.WithDetails(&errdetails.ResourceInfo{
+		ResourceType: resourceType,
+		ResourceName: name,
+	}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+func toProto(product *repository.Product) *pb.Product {
+	return &pb.Product{
+		Id:          product.ID,
+		CategoryId:  product.CategoryID,
+		Name:        product.Name,
+		Description: product.Description,
+		Price:       &pb.Money{CurrencyCode: product.Price.Currency, Units: product.Price.Units, Nanos: product.Price.Nanos},
+		Stock:       product.Stock,
+		CreatedAt:   timestamppb.New(product.CreatedAt),
+		UpdatedAt:   timestamppb.New(product.UpdatedAt),
+	}
 }
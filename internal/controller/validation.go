@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"regexp"
+
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/pb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Reasons of field violations, for clients to branch on.
+const (
+	reasonRequired   = "REQUIRED"
+	reasonInvalid    = "INVALID"
+	reasonOutOfRange = "OUT_OF_RANGE"
+	reasonNotFound   = "NOT_FOUND"
+)
+
+// violations collects the field-level problems of a request.
+type violations []*errdetails.BadRequest_FieldViolation
+
+// add records a problem with field, a proto field path such as price.currency_code.
+func (v *violations) add(field, reason, description string) {
+	*v = append(*v, &errdetails.BadRequest_FieldViolation{Field: field, Reason: reason, Description: description})
+}
+
+// err returns nil without violations, and otherwise an InvalidArgument status carrying them
+// in a BadRequest detail, its message being the first description.
+func (v violations) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	st := status.New(codes.InvalidArgument, v[0].Description)
+	if detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: v}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+func validateCreateCategory(req *pb.CreateCategoryRequest) violations {
+	var v violations
+	if req.Name == "" {
+		v.add("name", reasonRequired, "name is required")
+	}
+	if req.Description == "" {
+		v.add("description", reasonRequired, "description is required")
+	}
+	return v
+}
+
+func validateCreateProduct(req *pb.CreateProductRequest) violations {
+	var v violations
+	if req.Name == "" {
+		v.add("name", reasonRequired, "name is required")
+	}
+	if req.Description == "" {
+		v.add("description", reasonRequired, "description is required")
+	}
+	if req.CategoryId == 0 {
+		v.add("category_id", reasonRequired, "category id is required")
+	}
+	if req.Stock <= 0 {
+		v.add("stock", reasonOutOfRange, "stock must be positive")
+	}
+	validatePrice(&v, req.Price)
+	return v
+}
+
+func validateUpdateProduct(req *pb.UpdateProductRequest) violations {
+	var v violations
+	if req.CategoryId == 0 {
+		v.add("category_id", reasonRequired, "category id is required")
+	}
+	if req.ProductId == 0 {
+		v.add("product_id", reasonRequired, "product id is required")
+	}
+	if req.Name == nil && req.Description == nil && req.Price == nil && req.Stock == nil {
+		v.add("", reasonRequired, "at least one of name, description, price and stock is required")
+	}
+	if req.Name != nil && *req.Name == "" {
+		v.add("name", reasonRequired, "name must not be empty")
+	}
+	if req.Description != nil && *req.Description == "" {
+		v.add("description", reasonRequired, "description must not be empty")
+	}
+	if req.Stock != nil && *req.Stock < 0 {
+		v.add("stock", reasonOutOfRange, "stock must not be negative")
+	}
+	if req.Price != nil {
+		validatePrice(&v, req.Price)
+	}
+	return v
+}
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// validatePrice checks that price is a well-formed, positive google.type.Money amount.
+func validatePrice(v *violations, price *pb.Money) {
+	if price == nil {
+		v.add("price", reasonRequired, "price is required")
+		return
+	}
+	if !currencyCode.MatchString(price.CurrencyCode) {
+		v.add("price.currency_code", reasonInvalid, "currency code must be an ISO 4217 code such as USD")
+	}
+	switch {
+	case price.Nanos <= -1e9 || price.Nanos >= 1e9:
+		v.add("price.nanos", reasonOutOfRange, "nanos must be between -999999999 and 999999999")
+	case price.Units > 0 && price.Nanos < 0 || price.Units < 0 && price.Nanos > 0:
+		v.add("price", reasonInvalid, "units and nanos must have the same sign")
+	case price.Units < 0 || price.Nanos < 0 || price.Units == 0 && price.Nanos == 0:
+		v.add("price", reasonOutOfRange, "price must be positive")
+	}
+}
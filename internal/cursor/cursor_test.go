@@ -0,0 +1,126 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	codec := NewCodec([]byte("secret"), time.Hour)
+	tests := []struct {
+		name   string
+		cursor Cursor
+	}{
+		{"after id", Cursor{CategoryID: 42, PageSize: 10, AfterID: 1234}},
+		{"negative ids", Cursor{CategoryID: -1, PageSize: 1, AfterID: -99}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := codec.Decode(codec.Encode(tt.cursor))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.CategoryID != tt.cursor.CategoryID || got.PageSize != tt.cursor.PageSize || got.AfterID != tt.cursor.AfterID {
+				t.Errorf("Decode() = %+v, want %+v", got, tt.cursor)
+			}
+			if until := time.Until(got.ExpiresAt); until <= 0 || until > time.Hour {
+				t.Errorf("cursor expires in %v, want within the TTL", until)
+			}
+		})
+	}
+}
+
+func TestCodecRejects(t *testing.T) {
+	codec := NewCodec([]byte("secret"), time.Hour)
+	token := codec.Encode(Cursor{CategoryID: 1, PageSize: 10, AfterID: 5})
+
+	// Flip a bit in the middle of the payload, keeping valid base64.
+	tampered := []byte(token)
+	if tampered[2] == 'A' {
+		tampered[2] = 'B'
+	} else {
+		tampered[2] = 'A'
+	}
+
+	tests := []struct {
+		name  string
+		codec *Codec
+		token string
+		want  error
+	}{
+		{"empty", codec, "", ErrInvalid},
+		{"not base64", codec, "!!!", ErrInvalid},
+		{"truncated", codec, token[:len(token)-4], ErrInvalid},
+		{"tampered", codec, string(tampered), ErrInvalid},
+		{"other key", NewCodec([]byte("other"), time.Hour), token, ErrInvalid},
+		{"expired", codec, NewCodec([]byte("secret"), -time.Minute).Encode(Cursor{CategoryID: 1, PageSize: 10}), ErrExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.codec.Decode(tt.token); !errors.Is(err, tt.want) {
+				t.Errorf("Decode() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCodecIgnoresLegacyPagingState(t *testing.T) {
+	codec := NewCodec([]byte("secret"), time.Hour)
+	raw, err := base64.RawURLEncoding.DecodeString(codec.Encode(Cursor{CategoryID: 1, PageSize: 10, AfterID: 5}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := append(raw[:len(raw)-macSize:len(raw)-macSize], 0, 1, 2, 0xff)
+	token := base64.RawURLEncoding.EncodeToString(append(payload, codec.sign(payload)...))
+
+	got, err := codec.Decode(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.CategoryID != 1 || got.PageSize != 10 || got.AfterID != 5 {
+		t.Errorf("Decode() = %+v, want category 1, page size 10, after 5", got)
+	}
+}
+
+func TestCursorCheck(t *testing.T) {
+	c := Cursor{CategoryID: 1, PageSize: 10}
+	tests := []struct {
+		name       string
+		categoryID int64
+		pageSize   int32
+		wantErr    bool
+	}{
+		{"matching", 1, 10, false},
+		{"other category", 2, 10, true},
+		{"other page size", 1, 20, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := c.Check(tt.categoryID, tt.pageSize)
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrMismatch)) {
+				t.Errorf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewRandomCodecKeysDiffer(t *testing.T) {
+	a, err := NewRandomCodec(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewRandomCodec(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := a.Encode(Cursor{CategoryID: 1, PageSize: 1})
+	if _, err := b.Decode(token); !errors.Is(err, ErrInvalid) {
+		t.Errorf("a cursor of one random codec was accepted by another: %v", err)
+	}
+	if strings.ContainsAny(token, "+/=") {
+		t.Errorf("token %q is not unpadded URL-safe base64", token)
+	}
+}
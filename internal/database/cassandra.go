@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// CassandraTLSConfig holds the TLS settings for connecting to a Cassandra or ScyllaDB cluster.
+type CassandraTLSConfig struct {
+	Enabled            bool
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+}
+
+// CassandraConfig holds the configuration for connecting directly to a Cassandra or ScyllaDB cluster.
+type CassandraConfig struct {
+	Hosts           []string
+	Port            int
+	Username        string
+	Password        string
+	LocalDatacenter string
+	ProtocolVersion int
+	TLS             CassandraTLSConfig
+}
+
+// CassandraMethods defines the methods for interacting with a Cassandra cluster.
+type CassandraMethods interface {
+	Connect(ctx context.Context, cfg *CassandraConfig, timeout time.Duration) (*gocql.Session, error)
+}
+
+// CassandraDB represents a connection to a Cassandra or ScyllaDB cluster.
+type CassandraDB struct{}
+
+// NewCassandraDB initializes and returns a CassandraDB instance that implements CassandraMethods.
+func NewCassandraDB() CassandraMethods {
+	return &CassandraDB{}
+}
+
+// Connect establishes a connection to the cluster and returns a session.
+// Queries are routed to a replica owning the partition (token aware), preferring
+// nodes in the local datacenter when one is configured.
+func (db *CassandraDB) Connect(ctx context.Context, cfg *CassandraConfig, timeout time.Duration) (*gocql.Session, error) {
+	if len(cfg.Hosts) == 0 {
+		return nil, fmt.Errorf("at least one cassandra host is required")
+	}
+
+	cluster := gocql.NewCluster(cfg.Hosts...)
+	if cfg.Port != 0 {
+		cluster.Port = cfg.Port
+	}
+	if cfg.ProtocolVersion != 0 {
+		cluster.ProtoVersion = cfg.ProtocolVersion
+	}
+	cluster.Timeout = timeout
+	cluster.ConnectTimeout = timeout
+
+	if cfg.Username != "" {
+		cluster.Authenticator = gocql.PasswordAuthenticator{
+			Username: cfg.Username,
+			Password: cfg.Password,
+		}
+	}
+
+	fallback := gocql.RoundRobinHostPolicy()
+	if cfg.LocalDatacenter != "" {
+		fallback = gocql.DCAwareRoundRobinPolicy(cfg.LocalDatacenter)
+	}
+	cluster.PoolConfig.HostSelectionPolicy = gocql.TokenAwareHostPolicy(fallback, gocql.ShuffleReplicas())
+
+	if cfg.TLS.Enabled {
+		cluster.SslOpts = &gocql.SslOptions{
+			CaPath:                 cfg.TLS.CAFile,
+			CertPath:               cfg.TLS.CertFile,
+			KeyPath:                cfg.TLS.KeyFile,
+			EnableHostVerification: !cfg.TLS.InsecureSkipVerify,
+		}
+	}
+
+	session, err := cluster.CreateSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	slog.Info("Successfully connected to Cassandra", "hosts", cfg.Hosts, "datacenter", cfg.LocalDatacenter)
+
+	return session, nil
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresConfig holds the configuration for connecting to PostgreSQL.
+type PostgresConfig struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	Database string
+	SSLMode  string
+}
+
+// PostgresMethods defines the methods for interacting with PostgreSQL.
+type PostgresMethods interface {
+	Connect(ctx context.Context, cfg *PostgresConfig, timeout time.Duration) (*pgxpool.Pool, error)
+}
+
+// PostgresDB represents a connection to PostgreSQL.
+type PostgresDB struct{}
+
+// NewPostgresDB initializes and returns a PostgresDB instance that implements PostgresMethods.
+func NewPostgresDB() PostgresMethods {
+	return &PostgresDB{}
+}
+
+// Connect establishes a connection pool to PostgreSQL and verifies it with a ping.
+func (db *PostgresDB) Connect(ctx context.Context, cfg *PostgresConfig, timeout time.Duration) (*pgxpool.Pool, error) {
+	sslMode := cfg.SSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.User, cfg.Password),
+		Host:     net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		Path:     cfg.Database,
+		RawQuery: url.Values{"sslmode": {sslMode}, "connect_timeout": {strconv.Itoa(int(timeout.Seconds()))}}.Encode(),
+	}
+
+	poolCfg, err := pgxpool.ParseConfig(dsn.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse postgres config: %w", err)
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create postgres pool: %w", err)
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err := pool.Ping(pingCtx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to ping postgres: %w", err)
+	}
+
+	slog.Info("Successfully connected to PostgreSQL")
+
+	return pool, nil
+}
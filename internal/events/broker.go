@@ -0,0 +1,98 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/pb"
+)
+
+// Event types, as written to the outbox and used as the prefix of Pulsar message keys.
+// InventoryChanged is only published in-process, once the inventory of a product event is saved.
+const (
+	ProductCreated   = "create_product_event"
+	ProductUpdated   = "update_product_event"
+	InventoryChanged = "inventory_changed_event"
+)
+
+// subscriberBuffer is how many events a subscriber may lag behind before events are dropped for it.
+const subscriberBuffer = 64
+
+// InventoryChange is the stock level of a product after an inventory update.
+type InventoryChange struct {
+	ProductID  int64
+	CategoryID int64
+	StockCount int32
+	UpdatedAt  time.Time
+}
+
+// Event is a product change delivered to subscribers.
+type Event struct {
+	Type      string
+	Tenant    string
+	Product   *pb.Product
+	Inventory *InventoryChange
+}
+
+// TypeFromKey returns the event type of a Pulsar message key of the form "<event type>:<product id>".
+func TypeFromKey(key string) string {
+	eventType, _, _ := strings.Cut(key, ":")
+	return eventType
+}
+
+type subscriber struct {
+	ch     chan Event
+	filter func(Event) bool
+}
+
+// Broker fans events out to in-process subscribers. Publishing never blocks: a subscriber
+// whose buffer is full misses the event rather than stalling the consumer.
+type Broker struct {
+	mu          sync.RWMutex
+	subscribers map[*subscriber]struct{}
+}
+
+// NewBroker returns an empty Broker.
+func NewBroker() *Broker {
+	return &Broker{subscribers: make(map[*subscriber]struct{})}
+}
+
+// Subscribe returns a channel receiving the events accepted by filter until ctx is done,
+// at which point the channel is closed.
+func (b *Broker) Subscribe(ctx context.Context, filter func(Event) bool) <-chan Event {
+	sub := &subscriber{ch: make(chan Event, subscriberBuffer), filter: filter}
+
+	b.mu.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.subscribers, sub)
+		close(sub.ch)
+		b.mu.Unlock()
+	}()
+
+	return sub.ch
+}
+
+// Publish delivers event to every subscriber accepting it.
+func (b *Broker) Publish(event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subscribers {
+		if !sub.filter(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			slog.Warn("Dropping event for slow subscriber", "eventType", event.Type)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package events
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/queue"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/tenant"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/pb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// watchBuffer is how many events are read ahead of a watcher's handler. Once it is full the
+// reader stops pulling from Pulsar until the handler catches up.
+const watchBuffer = 32
+
+// ErrInvalidCursor is returned when a watch is resumed from a cursor that cannot be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Watcher streams product events from the event topic.
+type Watcher interface {
+	// Watch calls handle with every event published after cursor (or from now when cursor is empty),
+	// until ctx is done or handle returns an error.
+	Watch(ctx context.Context, cursor string, handle func(*pb.ProductEvent, string) error) error
+}
+
+// PulsarWatcher implements Watcher with a dedicated Pulsar reader per watch, so a slow
+// watcher only holds back its own reader.
+type PulsarWatcher struct {
+	queue  queue.PulsarMethods
+	client pulsar.Client
+}
+
+// NewPulsarWatcher returns a Watcher reading the topic configured in q.
+func NewPulsarWatcher(q queue.PulsarMethods, client pulsar.Client) *PulsarWatcher {
+	return &PulsarWatcher{queue: q, client: client}
+}
+
+// Watch calls handle with each event and the tenant it belongs to. Event cursors are the
+// serialized Pulsar message IDs.
+func (w *PulsarWatcher) Watch(ctx context.Context, cursor string, handle func(*pb.ProductEvent, string) error) error {
+	start := pulsar.LatestMessageID()
+	if cursor != "" {
+		data, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			return ErrInvalidCursor
+		}
+		if start, err = pulsar.DeserializeMessageID(data); err != nil {
+			return ErrInvalidCursor
+		}
+	}
+
+	reader, err := w.queue.CreatePulsarReader(ctx, w.client, start)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	messages := make(chan pulsar.Message, watchBuffer)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(messages)
+		for {
+			msg, err := reader.Next(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					readErr <- fmt.Errorf("failed to read event: %w", err)
+				}
+				return
+			}
+			select {
+			case messages <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for msg := range messages {
+		var product pb.Product
+		if err := json.Unmarshal(msg.Payload(), &product); err != nil {
+			slog.With("message_id", msg.ID()).Error("Failed to unmarshal product", "error", err)
+			continue
+		}
+
+		event := &pb.ProductEvent{
+			Cursor:      base64.RawURLEncoding.EncodeToString(msg.ID().Serialize()),
+			EventType:   TypeFromKey(msg.Key()),
+			Product:     &product,
+			PublishedAt: timestamppb.New(msg.PublishTime()),
+		}
+		if err := handle(event, msg.Properties()[tenant.PropertyKey]); err != nil {
+			return err
+		}
+	}
+
+	select {
+	case err := <-readErr:
+		return err
+	default:
+		return ctx.Err()
+	}
+}
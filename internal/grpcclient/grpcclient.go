@@ -0,0 +1,190 @@
+// Package grpcclient dials the product service the way the gateway is configured to: target
+// resolution, load balancing, keepalive, retries and default deadlines.
+package grpcclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/certs"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/pkg"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	_ "google.golang.org/grpc/health" // client side health checking
+	"google.golang.org/grpc/keepalive"
+)
+
+const (
+	// ServiceName is the service dialed connections call, and whose health they watch.
+	ServiceName = "products.ProductService"
+
+	defaultTimeout = 10 * time.Second
+)
+
+// readMethods are retried on failure; creating a category or a product is not, since a
+// retried create could store it twice.
+var readMethods = []string{"GetCategory", "BatchGetCategories", "GetProduct", "BatchGetProducts", "ListProducts", "CountProducts"}
+
+// Dial returns a client connection to the product service. Without a target or addresses it
+// connects to the local server on defaultPort. opts are appended to the configured options;
+// their interceptors run after the ones recording metrics.
+func Dial(cfg pkg.ProductService, defaultPort int, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	target := cfg.Target
+	switch {
+	case len(cfg.Addresses) > 0:
+		target = staticScheme + ":///" + strings.Join(cfg.Addresses, ",")
+	case target == "":
+		target = fmt.Sprintf("dns:///localhost:%d", defaultPort)
+	}
+
+	tlsConfig, err := certs.ClientConfig(context.Background(), cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS configuration: %w", err)
+	}
+	transportCreds := insecure.NewCredentials()
+	if tlsConfig != nil {
+		transportCreds = credentials.NewTLS(tlsConfig)
+	}
+
+	serviceConfig, err := buildServiceConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(transportCreds),
+		grpc.WithResolvers(staticBuilder{}),
+		grpc.WithDefaultServiceConfig(serviceConfig),
+		grpc.WithChainUnaryInterceptor(unaryMetrics),
+		grpc.WithChainStreamInterceptor(streamMetrics),
+	}
+	if len(cfg.Addresses) > 0 && cfg.TLS.ServerName != "" {
+		// The default authority of a static list is the whole list.
+		dialOpts = append(dialOpts, grpc.WithAuthority(cfg.TLS.ServerName))
+	}
+	if cfg.Keepalive.Time > 0 {
+		dialOpts = append(dialOpts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                cfg.Keepalive.Time,
+			Timeout:             cfg.Keepalive.Timeout,
+			PermitWithoutStream: cfg.Keepalive.PermitWithoutStream,
+		}))
+	}
+
+	return grpc.NewClient(target, append(dialOpts, opts...)...)
+}
+
+type methodName struct {
+	Service string `json:"service"`
+	Method  string `json:"method,omitempty"`
+}
+
+type retryPolicy struct {
+	MaxAttempts          int      `json:"maxAttempts"`
+	InitialBackoff       string   `json:"initialBackoff"`
+	MaxBackoff           string   `json:"maxBackoff"`
+	BackoffMultiplier    float64  `json:"backoffMultiplier"`
+	RetryableStatusCodes []string `json:"retryableStatusCodes"`
+}
+
+type methodConfig struct {
+	Name        []methodName `json:"name"`
+	Timeout     string       `json:"timeout,omitempty"`
+	RetryPolicy *retryPolicy `json:"retryPolicy,omitempty"`
+}
+
+// buildServiceConfig returns the service config of the connection: its load balancing policy,
+// which skips backends whose health is not SERVING, and, per method, a default deadline and for reads a retry policy. Deadlines do not apply to
+// WatchProducts, which streams until the client goes away.
+func buildServiceConfig(cfg pkg.ProductService) (string, error) {
+	policy := cfg.LoadBalancing
+	if policy == "" {
+		policy = "round_robin"
+	}
+	if policy != "round_robin" && policy != "pick_first" {
+		return "", fmt.Errorf("unknown load balancing policy %q, expected round_robin or pick_first", policy)
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	for method := range cfg.Timeouts {
+		if method == "WatchProducts" || !knownMethod(method) {
+			return "", fmt.Errorf("no timeout can be configured for %q", method)
+		}
+	}
+
+	var retry *retryPolicy
+	if cfg.Retry.MaxAttempts > 1 {
+		codes := cfg.Retry.RetryableStatusCodes
+		if len(codes) == 0 {
+			codes = []string{"UNAVAILABLE"}
+		}
+		retry = &retryPolicy{
+			MaxAttempts:          cfg.Retry.MaxAttempts,
+			InitialBackoff:       duration(cfg.Retry.InitialBackoff, 100*time.Millisecond),
+			MaxBackoff:           duration(cfg.Retry.MaxBackoff, time.Second),
+			BackoffMultiplier:    cfg.Retry.BackoffMultiplier,
+			RetryableStatusCodes: codes,
+		}
+		if retry.BackoffMultiplier <= 0 {
+			retry.BackoffMultiplier = 2
+		}
+	}
+
+	var methods []methodConfig
+	for _, method := range unaryMethods() {
+		mc := methodConfig{Name: []methodName{{Service: ServiceName, Method: method}}, Timeout: duration(timeout, 0)}
+		if override, ok := cfg.Timeouts[method]; ok {
+			mc.Timeout = duration(override, timeout)
+		}
+		if isRead(method) {
+			mc.RetryPolicy = retry
+		}
+		methods = append(methods, mc)
+	}
+
+	serviceConfig, err := json.Marshal(map[string]any{
+		"loadBalancingConfig": []map[string]any{{policy: map[string]any{}}},
+		"methodConfig":        methods,
+		"healthCheckConfig":   map[string]string{"serviceName": ServiceName},
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(serviceConfig), nil
+}
+
+func unaryMethods() []string {
+	return append([]string{"CreateCategory", "CreateProduct", "UpdateProduct"}, readMethods...)
+}
+
+func knownMethod(method string) bool {
+	for _, m := range unaryMethods() {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func isRead(method string) bool {
+	for _, m := range readMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// duration formats d as a service config duration, def when d is not positive.
+func duration(d, def time.Duration) string {
+	if d <= 0 {
+		d = def
+	}
+	return fmt.Sprintf("%.9fs", d.Seconds())
+}
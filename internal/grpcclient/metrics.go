@@ -0,0 +1,57 @@
+package grpcclient
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	clientHandled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_client_handled_total",
+		Help: "RPCs completed by the gateway, by method, type (unary or stream) and status code.",
+	}, []string{"method", "type", "code"})
+	clientHandling = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_client_handling_seconds",
+		Help:    "Time unary RPCs of the gateway took, retries included, by method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+)
+
+func unaryMetrics(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	clientHandling.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	clientHandled.WithLabelValues(method, "unary", status.Code(err).String()).Inc()
+	return err
+}
+
+func streamMetrics(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		clientHandled.WithLabelValues(method, "stream", status.Code(err).String()).Inc()
+		return nil, err
+	}
+	return &clientStream{ClientStream: stream, method: method}, nil
+}
+
+// clientStream records the status of a stream when receiving from it fails, which is how
+// streams end.
+type clientStream struct {
+	grpc.ClientStream
+	method string
+	done   bool
+}
+
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil && !s.done {
+		s.done = true
+		clientHandled.WithLabelValues(s.method, "stream", status.Code(err).String()).Inc()
+	}
+	return err
+}
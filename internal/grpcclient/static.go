@@ -0,0 +1,40 @@
+package grpcclient
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc/resolver"
+)
+
+// staticScheme resolves static:///host1:port,host2:port to the listed addresses.
+const staticScheme = "static"
+
+type staticBuilder struct{}
+
+func (staticBuilder) Scheme() string {
+	return staticScheme
+}
+
+func (staticBuilder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	var addresses []resolver.Address
+	for _, address := range strings.Split(target.Endpoint(), ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			addresses = append(addresses, resolver.Address{Addr: address})
+		}
+	}
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("no addresses in target %q", target.URL.String())
+	}
+	if err := cc.UpdateState(resolver.State{Addresses: addresses}); err != nil {
+		return nil, err
+	}
+	return staticResolver{}, nil
+}
+
+// staticResolver never changes its addresses.
+type staticResolver struct{}
+
+func (staticResolver) ResolveNow(resolver.ResolveNowOptions) {}
+
+func (staticResolver) Close() {}
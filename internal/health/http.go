@@ -0,0 +1,67 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Live answers /healthz: the process is up and serving HTTP.
+func Live(w http.ResponseWriter, _ *http.Request) {
+	writeStatus(w, http.StatusOK, "SERVING", "")
+}
+
+// Readiness answers /readyz with the health of a gRPC backend service, so that the gateway
+// only receives traffic it can forward.
+type Readiness struct {
+	client   healthpb.HealthClient
+	service  string
+	timeout  time.Duration
+	stopping atomic.Bool
+}
+
+// NewReadiness returns a Readiness asking conn for the status of service, waiting up to timeout.
+func NewReadiness(conn grpc.ClientConnInterface, service string, timeout time.Duration) *Readiness {
+	return &Readiness{client: healthpb.NewHealthClient(conn), service: service, timeout: timeout}
+}
+
+// Stop reports the gateway not ready from now on, while it drains before shutting down.
+func (r *Readiness) Stop() {
+	r.stopping.Store(true)
+}
+
+func (r *Readiness) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if r.stopping.Load() {
+		writeStatus(w, http.StatusServiceUnavailable, "NOT_SERVING", "the gateway is shutting down")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), r.timeout)
+	defer cancel()
+	resp, err := r.client.Check(ctx, &healthpb.HealthCheckRequest{Service: r.service})
+	if err != nil {
+		writeStatus(w, http.StatusServiceUnavailable, "UNKNOWN", err.Error())
+		return
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		writeStatus(w, http.StatusServiceUnavailable, resp.Status.String(), "")
+		return
+	}
+	writeStatus(w, http.StatusOK, resp.Status.String(), "")
+}
+
+func writeStatus(w http.ResponseWriter, code int, status, reason string) {
+	body := map[string]string{"status": status}
+	if reason != "" {
+		body["error"] = reason
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,110 @@
+// Package health reports whether the gRPC services can serve, through grpc.health.v1 on the
+// server and /healthz and /readyz on the gateway.
+package health
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Check returns an error when a dependency is unhealthy.
+type Check func(ctx context.Context) error
+
+type dependency struct {
+	name  string
+	check Check
+}
+
+// Monitor checks dependencies periodically and sets each service SERVING only while all the
+// dependencies it needs are healthy. The overall status, the empty service name, needs every
+// dependency.
+type Monitor struct {
+	server       *health.Server
+	dependencies []dependency
+	services     map[string][]string // service -> names of the dependencies it needs
+	timeout      time.Duration
+}
+
+// NewMonitor returns a Monitor updating server; each check may take up to timeout.
+func NewMonitor(server *health.Server, timeout time.Duration) *Monitor {
+	return &Monitor{server: server, services: make(map[string][]string), timeout: timeout}
+}
+
+// Add registers a dependency needed by services.
+func (m *Monitor) Add(name string, check Check, services ...string) {
+	m.dependencies = append(m.dependencies, dependency{name: name, check: check})
+	for _, service := range services {
+		m.services[service] = append(m.services[service], name)
+	}
+}
+
+// Run checks the dependencies every interval until ctx is done. Services start NOT_SERVING
+// until the first round of checks passes.
+func (m *Monitor) Run(ctx context.Context, interval time.Duration) {
+	for service := range m.services {
+		m.server.SetServingStatus(service, healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+	m.server.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	healthy := make(map[string]bool, len(m.dependencies))
+	for {
+		for _, dep := range m.dependencies {
+			checkCtx, cancel := context.WithTimeout(ctx, m.timeout)
+			err := dep.check(checkCtx)
+			cancel()
+
+			if was, checked := healthy[dep.name]; !checked || was != (err == nil) {
+				if err != nil {
+					slog.Warn("dependency is unhealthy", "dependency", dep.name, "error", err)
+				} else {
+					slog.Info("dependency is healthy", "dependency", dep.name)
+				}
+			}
+			healthy[dep.name] = err == nil
+		}
+		m.update(healthy)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// update sets the status of every service from the health of its dependencies.
+func (m *Monitor) update(healthy map[string]bool) {
+	all := true
+	for _, dep := range m.dependencies {
+		all = all && healthy[dep.name]
+	}
+	m.server.SetServingStatus("", servingStatus(all))
+
+	services := make([]string, 0, len(m.services))
+	for service := range m.services {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	for _, service := range services {
+		ok := true
+		for _, name := range m.services[service] {
+			ok = ok && healthy[name]
+		}
+		m.server.SetServingStatus(service, servingStatus(ok))
+	}
+}
+
+func servingStatus(ok bool) healthpb.HealthCheckResponse_ServingStatus {
+	if ok {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
@@ -7,7 +7,10 @@ import (
 	"time"
 
 	"github.com/apache/pulsar-client-go/pulsar"
-	"github.com/gocql/gocql"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/events"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/repository"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/requestid"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/tenant"
 	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/pb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
@@ -30,7 +33,9 @@ type ProductPayload struct {
 	UpdatedAt   *timestamppb.Timestamp
 }
 
-func ConsumeMessages(ctx context.Context, consumer pulsar.Consumer, session *gocql.Session) {
+// ConsumeMessages saves the inventory of every product event and forwards the events to broker for subscribers,
+// followed by an InventoryChanged event once the inventory is saved.
+func ConsumeMessages(ctx context.Context, consumer pulsar.Consumer, repo repository.Repository, broker *events.Broker) {
 	for {
 		select {
 		case <-ctx.Done():
@@ -44,34 +49,55 @@ func ConsumeMessages(ctx context.Context, consumer pulsar.Consumer, session *goc
 				continue
 			}
 
+			consumerLag.WithLabelValues(msg.Topic()).Observe(time.Since(msg.PublishTime()).Seconds())
+
 			var product pb.Product
 			if err := json.Unmarshal(msg.Payload(), &product); err != nil {
 				slog.With("message_id", msg.ID()).Error("Failed to unmarshal product", "error", err)
 				consumer.Nack(msg) // Added to avoid processing broken messages
+				consumed.WithLabelValues(msg.Topic(), "nack").Inc()
 				continue
 			}
 
 			goTime := product.CreatedAt.AsTime()
+			tenantID := msg.Properties()[tenant.PropertyKey]
+			requestID := msg.Properties()[requestid.PropertyKey]
 
-			slog.With("product_id", product.Id).Info("Received inventory message")
+			slog.With("product_id", product.Id, "request_id", requestID).Info("Received inventory message")
 
-			if err := SaveInventory(ctx, session, product.Id, product.CategoryId, product.Stock, goTime); err != nil {
-				slog.With("product_id", product.Id).Error("Failed to save inventory", "error", err)
+			inventory := &repository.Inventory{
+				ProductID:  product.Id,
+				CategoryID: product.CategoryId,
+				StockCount: product.Stock,
+				CreatedAt:  goTime,
+			}
+			// Inventory is stored in the keyspace of the tenant the event came from.
+			msgCtx := requestid.NewContext(tenant.NewContext(ctx, tenantID), requestID)
+			if err := repo.SaveInventory(msgCtx, inventory); err != nil {
+				slog.With("product_id", product.Id, "request_id", requestID).Error("Failed to save inventory", "error", err)
 				consumer.Nack(msg)
+				consumed.WithLabelValues(msg.Topic(), "nack").Inc()
 				continue
 			}
 
+			broker.Publish(events.Event{
+				Type:    events.TypeFromKey(msg.Key()),
+				Tenant:  tenantID,
+				Product: &product,
+			})
+			broker.Publish(events.Event{
+				Type:   events.InventoryChanged,
+				Tenant: tenantID,
+				Inventory: &events.InventoryChange{
+					ProductID:  product.Id,
+					CategoryID: product.CategoryId,
+					StockCount: product.Stock,
+					UpdatedAt:  time.Now(),
+				},
+			})
+
 			consumer.Ack(msg)
+			consumed.WithLabelValues(msg.Topic(), "ack").Inc()
 		}
 	}
 }
-
-func SaveInventory(ctx context.Context, session *gocql.Session, productId int64, categoryId int64, stock int32, createdAt time.Time) error {
-	query := `INSERT INTO products_keyspace_v2.inventory 
-              (product_id, category_id, stock_count, created_at, last_updated_at) 
-              VALUES (?, ?, ?, ?, ?)`
-
-	return session.Query(query, productId, categoryId, stock, createdAt, time.Now()).
-		WithContext(ctx).
-		Exec()
-}
@@ -0,0 +1,57 @@
+package helpers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/repository"
+)
+
+var (
+	outboxBacklog = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "outbox_backlog_events",
+		Help: "Events waiting in the outbox after the last relay.",
+	})
+	outboxOldestAge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "outbox_oldest_event_age_seconds",
+		Help: "Age of the oldest event waiting in the outbox after the last relay, 0 when it is empty.",
+	})
+	relayLag = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "outbox_relay_lag_seconds",
+		Help:    "Time from writing an outbox event to publishing it.",
+		Buckets: []float64{.5, 1, 2.5, 5, 10, 30, 60, 300, 900},
+	})
+
+	published = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pulsar_messages_published_total",
+		Help: "Messages published to Pulsar, by topic and result (ok or error).",
+	}, []string{"topic", "result"})
+	consumed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pulsar_messages_consumed_total",
+		Help: "Messages received from Pulsar, by topic and result (ack or nack).",
+	}, []string{"topic", "result"})
+	consumerLag = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "pulsar_consumer_lag_seconds",
+		Help:    "Time from publishing a message to receiving it, by topic.",
+		Buckets: []float64{.01, .05, .1, .5, 1, 5, 10, 30, 60, 300},
+	}, []string{"topic"})
+)
+
+// reportBacklog updates the outbox backlog gauges. Failures are only logged, the backlog
+// being informational.
+func reportBacklog(ctx context.Context, repo repository.Repository) {
+	backlog, err := repo.OutboxBacklog(ctx)
+	if err != nil {
+		slog.Warn("failed to measure the outbox backlog", "error", err)
+		return
+	}
+	outboxBacklog.Set(float64(backlog.Events))
+	if backlog.Oldest.IsZero() {
+		outboxOldestAge.Set(0)
+	} else {
+		outboxOldestAge.Set(time.Since(backlog.Oldest).Seconds())
+	}
+}
@@ -8,67 +8,36 @@ import (
 	"time"
 
 	"github.com/apache/pulsar-client-go/pulsar"
-	"github.com/gocql/gocql"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/repository"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/requestid"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/tenant"
 	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/pb"
 )
 
-type ProductOutbox struct {
-	Id        gocql.UUID
-	Bucket    string
-	EventType string
-	Payload   string
-}
-
-var (
-	getProductsFromOutboxQuery = `
-		SELECT id, bucket, payload, event_type 
-		FROM products_keyspace_v2.products_outbox 
-		WHERE bucket = ? 
-		ORDER BY id ASC;
-	`
-)
-
-func ProcessMessages(ctx context.Context, session *gocql.Session, producer pulsar.Producer) error {
-	bucket := getCurrentBucket()
-
-	messages, err := fetchMessages(ctx, session, bucket)
-	if err != nil {
-		return fmt.Errorf("failed to fetch messages: %w", err)
-	}
+// outboxBatchSize is the maximum number of outbox events relayed per poll.
+const outboxBatchSize = 100
 
-	for _, message := range messages {
-		if err := sendToPulsar(ctx, producer, message, session); err != nil {
-			slog.Error("Failed to send message to Pulsar", "error", err, "messageID", message.Id)
-			continue
+func ProcessMessages(ctx context.Context, repo repository.Repository, producer pulsar.Producer) error {
+	if err := repo.RelayOutbox(ctx, outboxBatchSize, func(ctx context.Context, event *repository.OutboxEvent) error {
+		if err := sendToPulsar(ctx, producer, event); err != nil {
+			published.WithLabelValues(producer.Topic(), "error").Inc()
+			return err
 		}
-	}
-	return nil
-}
-
-func fetchMessages(ctx context.Context, session *gocql.Session, bucket string) ([]ProductOutbox, error) {
-	var productsData []ProductOutbox
-
-	iter := session.Query(getProductsFromOutboxQuery, bucket).WithContext(ctx).Iter()
-	defer iter.Close()
-
-	for {
-		var newProduct ProductOutbox
-		if !iter.Scan(&newProduct.Id, &newProduct.Bucket, &newProduct.Payload, &newProduct.EventType) {
-			break
+		published.WithLabelValues(producer.Topic(), "ok").Inc()
+		if !event.CreatedAt.IsZero() {
+			relayLag.Observe(time.Since(event.CreatedAt).Seconds())
 		}
-		productsData = append(productsData, newProduct)
-	}
-
-	if err := iter.Close(); err != nil {
-		return nil, err
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to relay messages: %w", err)
 	}
-
-	return productsData, nil
+	reportBacklog(ctx, repo)
+	return nil
 }
 
-func sendToPulsar(ctx context.Context, producer pulsar.Producer, message ProductOutbox, session *gocql.Session) error {
+func sendToPulsar(ctx context.Context, producer pulsar.Producer, message *repository.OutboxEvent) error {
 	var product pb.Product
-	if err := json.Unmarshal([]byte(message.Payload), &product); err != nil {
+	if err := json.Unmarshal(message.Payload, &product); err != nil {
 		return fmt.Errorf("failed to unmarshal payload: %w", err)
 	}
 
@@ -79,10 +48,19 @@ func sendToPulsar(ctx context.Context, producer pulsar.Producer, message Product
 
 	messageChan := make(chan error, 1)
 
-	producer.SendAsync(ctx, &pulsar.ProducerMessage{
+	msg := &pulsar.ProducerMessage{
 		Key:     fmt.Sprintf("%s:%d", message.EventType, product.Id),
 		Payload: payload,
-	}, func(_ pulsar.MessageID, _ *pulsar.ProducerMessage, err error) {
+	}
+	msg.Properties = make(map[string]string)
+	if tenantID, ok := tenant.FromContext(ctx); ok {
+		msg.Properties[tenant.PropertyKey] = tenantID
+	}
+	if message.RequestID != "" {
+		msg.Properties[requestid.PropertyKey] = message.RequestID
+	}
+
+	producer.SendAsync(ctx, msg, func(_ pulsar.MessageID, _ *pulsar.ProducerMessage, err error) {
 		select {
 		case messageChan <- err:
 		default:
@@ -102,22 +80,7 @@ func sendToPulsar(ctx context.Context, producer pulsar.Producer, message Product
 		return fmt.Errorf("context canceled while publishing message")
 	}
 
-	slog.Info("Message sent to Pulsar", "messageID", message.Id)
+	slog.Info("Message sent to Pulsar", "messageID", message.ID, "request_id", message.RequestID)
 
-	return deleteMessage(ctx, session, message.Id)
-}
-
-func deleteMessage(ctx context.Context, session *gocql.Session, msgID gocql.UUID) error {
-	bucket := getCurrentBucket()
-
-	slog.Info("Deleting message", "messageID", msgID)
-
-	return session.Query(`
-		DELETE FROM products_keyspace_v2.products_outbox WHERE bucket = ? AND id = ?`,
-		bucket, msgID,
-	).WithContext(ctx).Exec()
-}
-
-func getCurrentBucket() string {
-	return time.Now().Format("2006-01-02")
+	return nil
 }
@@ -0,0 +1,95 @@
+// Package interceptor provides the gRPC server interceptors every RPC goes through: access
+// logs, metrics and panic recovery.
+package interceptor
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/requestid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryLogging logs every RPC once it completes, with its method, status code, latency, peer
+// and request id. It must run after requestid.UnaryServerInterceptor.
+func UnaryLogging() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logRPC(ctx, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamLogging is the streaming counterpart of UnaryLogging, logging streams when they end.
+func StreamLogging() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logRPC(ss.Context(), info.FullMethod, start, err)
+		return err
+	}
+}
+
+func logRPC(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+	attrs := []any{
+		"method", method,
+		"code", code.String(),
+		"duration", time.Since(start),
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		attrs = append(attrs, "peer", p.Addr.String())
+	}
+	if id, ok := requestid.FromContext(ctx); ok {
+		attrs = append(attrs, "request_id", id)
+	}
+
+	switch code {
+	case codes.OK:
+		slog.Info("rpc finished", attrs...)
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable, codes.DeadlineExceeded:
+		slog.Error("rpc failed", append(attrs, "error", status.Convert(err).Message())...)
+	default:
+		slog.Warn("rpc failed", append(attrs, "error", status.Convert(err).Message())...)
+	}
+}
+
+// UnaryRecovery turns a panic in a handler into an Internal error instead of crashing the
+// server, logging the stack trace.
+func UnaryRecovery() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recovered(ctx, info.FullMethod, r)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamRecovery is the streaming counterpart of UnaryRecovery.
+func StreamRecovery() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recovered(ss.Context(), info.FullMethod, r)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+func recovered(ctx context.Context, method string, r any) error {
+	attrs := []any{"method", method, "panic", r, "stack", string(debug.Stack())}
+	if id, ok := requestid.FromContext(ctx); ok {
+		attrs = append(attrs, "request_id", id)
+	}
+	slog.Error("recovered from a panic in an rpc handler", attrs...)
+	return status.Error(codes.Internal, "internal error")
+}
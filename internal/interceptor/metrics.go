@@ -0,0 +1,44 @@
+package interceptor
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	serverHandled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_handled_total",
+		Help: "RPCs completed by the server, by method, type (unary or stream) and status code.",
+	}, []string{"method", "type", "code"})
+	serverHandling = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_server_handling_seconds",
+		Help:    "Time the server took to complete unary RPCs, by method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+)
+
+// UnaryMetrics records the status and latency of every RPC.
+func UnaryMetrics() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		serverHandling.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+		serverHandled.WithLabelValues(info.FullMethod, "unary", status.Code(err).String()).Inc()
+		return resp, err
+	}
+}
+
+// StreamMetrics records the status of every stream. Streams last as long as their watchers, so
+// their duration is not recorded.
+func StreamMetrics() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		err := handler(srv, ss)
+		serverHandled.WithLabelValues(info.FullMethod, "stream", status.Code(err).String()).Inc()
+		return err
+	}
+}
@@ -0,0 +1,139 @@
+package limits
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/99designs/gqlgen/complexity"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/apikey"
+	"golang.org/x/time/rate"
+)
+
+const (
+	errBudgetExceeded = "BUDGET_EXCEEDED"
+
+	// maxIdleClients bounds the limiters kept for anonymous clients before full ones are dropped.
+	maxIdleClients = 10_000
+)
+
+type clientKey struct{}
+
+// client is who an operation is charged to: an API key or, without one, an address.
+type client struct {
+	id     string
+	apiKey bool
+}
+
+// Budget limits how much complexity each client may spend per minute. Requests authenticated
+// by apikey.Middleware are budgeted per key, the others per IP address.
+type Budget struct {
+	perMinute int
+	keys      map[string]int
+
+	es       graphql.ExecutableSchema
+	mu       sync.Mutex
+	limiters map[client]*rate.Limiter
+}
+
+var _ interface {
+	graphql.OperationContextMutator
+	graphql.HandlerExtension
+} = &Budget{}
+
+// NewBudget returns a Budget of perMinute complexity points per client, keys overriding it by API key id.
+func NewBudget(perMinute int, keys map[string]int) *Budget {
+	return &Budget{perMinute: perMinute, keys: keys, limiters: make(map[client]*rate.Limiter)}
+}
+
+// Middleware stores who the request is charged to in its context. It must run after apikey.Middleware.
+func (b *Budget) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := client{id: r.RemoteAddr}
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			c.id = host
+		}
+		if key, ok := apikey.FromContext(r.Context()); ok {
+			c = client{id: key.ID, apiKey: true}
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientKey{}, c)))
+	})
+}
+
+func (b *Budget) ExtensionName() string {
+	return "Budget"
+}
+
+func (b *Budget) Validate(schema graphql.ExecutableSchema) error {
+	b.es = schema
+	return nil
+}
+
+func (b *Budget) MutateOperationContext(ctx context.Context, opCtx *graphql.OperationContext) *gqlerror.Error {
+	c, ok := ctx.Value(clientKey{}).(client)
+	if !ok {
+		return nil
+	}
+
+	// Reuse the complexity computed by the ComplexityLimit extension when it ran first.
+	cost := 0
+	if stats, ok := opCtx.Stats.GetExtension("ComplexityLimit").(*extension.ComplexityStats); ok {
+		cost = stats.Complexity
+	} else if op := opCtx.Doc.Operations.ForName(opCtx.OperationName); op != nil {
+		cost = complexity.Calculate(b.es, op, opCtx.Variables)
+	}
+
+	limit := b.perMinute
+	if keyLimit, ok := b.keys[c.id]; ok && c.apiKey {
+		limit = keyLimit
+	}
+
+	now := time.Now()
+	reservation := b.limiter(c, limit).ReserveN(now, cost)
+	if !reservation.OK() {
+		err := gqlerror.Errorf("operation has complexity %d, which exceeds the budget of %d per minute", cost, limit)
+		errcode.Set(err, errBudgetExceeded)
+		return err
+	}
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		retryAfter := int(math.Ceil(delay.Seconds()))
+		err := gqlerror.Errorf("complexity budget of %d per minute exhausted, retry in %d seconds", limit, retryAfter)
+		errcode.Set(err, errBudgetExceeded)
+		err.Extensions["retryAfter"] = retryAfter
+		return err
+	}
+	return nil
+}
+
+// limiter returns the limiter of c, refilling limit points per minute.
+func (b *Budget) limiter(c client, limit int) *rate.Limiter {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if l, ok := b.limiters[c]; ok {
+		return l
+	}
+	if len(b.limiters) >= maxIdleClients {
+		b.dropFull(time.Now())
+	}
+	l := rate.NewLimiter(rate.Limit(float64(limit)/60), limit)
+	b.limiters[c] = l
+	return l
+}
+
+// dropFull forgets the limiters that refilled completely, which behave like new ones.
+func (b *Budget) dropFull(now time.Time) {
+	for c, l := range b.limiters {
+		if l.TokensAt(now) >= float64(l.Burst()) {
+			delete(b.limiters, c)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/gocql/gocql"
+	"gopkg.in/inf.v0"
+)
+
+// backfillCurrency is the currency of every price stored before currencies were recorded.
+const backfillCurrency = "USD"
+
+// backfillPriceAmount copies the float price of products written before price_amount existed
+// into it, using the float's shortest decimal form so that 19.99 becomes exactly 19.99.
+// Rows that already have an amount are skipped, so it can be re-run.
+func backfillPriceAmount(session *gocql.Session, keyspace string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		scan := fmt.Sprintf(`SELECT category_id, id, price, price_amount FROM %s.products`, keyspace)
+		update := fmt.Sprintf(`UPDATE %s.products SET price_amount = ?, currency = ? WHERE category_id = ? AND id = ?`, keyspace)
+
+		iter := session.Query(scan).WithContext(ctx).PageSize(500).Iter()
+		var (
+			categoryID, id int64
+			price          float32
+			amount         *inf.Dec
+			updated        int
+		)
+		for iter.Scan(&categoryID, &id, &price, &amount) {
+			if amount != nil {
+				continue
+			}
+
+			decimal, ok := new(inf.Dec).SetString(strconv.FormatFloat(float64(price), 'f', -1, 32))
+			if !ok {
+				iter.Close()
+				return fmt.Errorf("cannot convert price %v of product %d", price, id)
+			}
+			if err := session.Query(update, decimal, backfillCurrency, categoryID, id).WithContext(ctx).Exec(); err != nil {
+				iter.Close()
+				return fmt.Errorf("failed to backfill product %d: %w", id, err)
+			}
+			updated++
+		}
+		if err := iter.Close(); err != nil {
+			return err
+		}
+
+		slog.Info("backfilled product prices", "keyspace", keyspace, "products", updated)
+		return nil
+	}
+}
+
+// backfillProductCategories records the category of products written before product_categories
+// existed. Inserts are idempotent, so it can be re-run.
+func backfillProductCategories(session *gocql.Session, keyspace string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		scan := fmt.Sprintf(`SELECT category_id, id FROM %s.products`, keyspace)
+		insert := fmt.Sprintf(`INSERT INTO %s.product_categories (id, category_id) VALUES (?, ?)`, keyspace)
+
+		iter := session.Query(scan).WithContext(ctx).PageSize(500).Iter()
+		var (
+			categoryID, id int64
+			inserted       int
+		)
+		for iter.Scan(&categoryID, &id) {
+			if err := session.Query(insert, id, categoryID).WithContext(ctx).Exec(); err != nil {
+				iter.Close()
+				return fmt.Errorf("failed to backfill product %d: %w", id, err)
+			}
+			inserted++
+		}
+		if err := iter.Close(); err != nil {
+			return err
+		}
+
+		slog.Info("backfilled product categories", "keyspace", keyspace, "products", inserted)
+		return nil
+	}
+}
@@ -0,0 +1,240 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+const (
+	// lockTTL bounds how long a crashed migrator can hold the lock.
+	lockTTL          = 5 * time.Minute
+	lockRetryBackoff = 2 * time.Second
+	// lockRefresh is how often a held lock's TTL is renewed, leaving time for a slow refresh.
+	lockRefresh = lockTTL / 5
+)
+
+// errLockLost cancels a migration run whose lock could not be renewed.
+var errLockLost = errors.New("lost the migration lock")
+
+// CQLDriver migrates a single keyspace. Concurrent migrators are serialised with a
+// lightweight-transaction lock row that expires after lockTTL unless its owner renews it.
+type CQLDriver struct {
+	session  *gocql.Session
+	keyspace string
+	owner    string
+}
+
+// NewCQLDriver returns a Driver migrating keyspace through session.
+func NewCQLDriver(session *gocql.Session, keyspace string) *CQLDriver {
+	hostname, _ := os.Hostname()
+	return &CQLDriver{
+		session:  session,
+		keyspace: keyspace,
+		owner:    fmt.Sprintf("%s/%d/%s", hostname, os.Getpid(), gocql.TimeUUID()),
+	}
+}
+
+func (d *CQLDriver) Target() string {
+	return "keyspace " + d.keyspace
+}
+
+func (d *CQLDriver) Init(ctx context.Context) error {
+	statements := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s.schema_migrations (
+			version int PRIMARY KEY,
+			name text,
+			checksum text,
+			applied_at timestamp
+		)`, d.keyspace),
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s.schema_migrations_lock (
+			name text PRIMARY KEY,
+			owner text,
+			acquired_at timestamp
+		)`, d.keyspace),
+	}
+	for _, stmt := range statements {
+		if err := d.session.Query(stmt).WithContext(ctx).Exec(); err != nil {
+			return err
+		}
+	}
+	return d.session.AwaitSchemaAgreement(ctx)
+}
+
+func (d *CQLDriver) Lock(ctx context.Context) (context.Context, func(), error) {
+	acquire := fmt.Sprintf(`INSERT INTO %s.schema_migrations_lock (name, owner, acquired_at) VALUES ('migrations', ?, ?) IF NOT EXISTS USING TTL %d`,
+		d.keyspace, int(lockTTL.Seconds()))
+
+	for {
+		existing := map[string]interface{}{}
+		applied, err := d.session.Query(acquire, d.owner, time.Now()).WithContext(ctx).MapScanCAS(existing)
+		if err != nil {
+			return nil, nil, err
+		}
+		if applied {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, nil, fmt.Errorf("lock held by %v: %w", existing["owner"], ctx.Err())
+		case <-time.After(lockRetryBackoff):
+		}
+	}
+
+	locked, cancel := context.WithCancelCause(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.renewLock(locked, cancel)
+	}()
+
+	release := fmt.Sprintf(`DELETE FROM %s.schema_migrations_lock WHERE name = 'migrations' IF owner = ?`, d.keyspace)
+	return locked, func() {
+		cancel(nil)
+		<-done
+		// The lock expires on its own if this fails.
+		_, _ = d.session.Query(release, d.owner).MapScanCAS(map[string]interface{}{})
+	}, nil
+}
+
+// renewLock refreshes the TTL of the lock row every lockRefresh until ctx is done, so long Go
+// migrations keep the lock. When a refresh fails, it cancels the run rather than let another
+// migrator take the lock concurrently.
+func (d *CQLDriver) renewLock(ctx context.Context, cancel context.CancelCauseFunc) {
+	// Only regular columns carry the new TTL, so both are set to keep the row alive.
+	renew := fmt.Sprintf(`UPDATE %s.schema_migrations_lock USING TTL %d SET owner = ?, acquired_at = ? WHERE name = 'migrations' IF owner = ?`,
+		d.keyspace, int(lockTTL.Seconds()))
+
+	ticker := time.NewTicker(lockRefresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		existing := map[string]interface{}{}
+		applied, err := d.session.Query(renew, d.owner, time.Now(), d.owner).WithContext(ctx).MapScanCAS(existing)
+		if ctx.Err() != nil {
+			return
+		}
+		switch {
+		case err != nil:
+			err = fmt.Errorf("%w: %w", errLockLost, err)
+		case !applied:
+			err = fmt.Errorf("%w: now held by %v", errLockLost, existing["owner"])
+		default:
+			continue
+		}
+		slog.Error("aborting migrations", "target", d.Target(), "error", err)
+		cancel(err)
+		return
+	}
+}
+
+func (d *CQLDriver) Applied(ctx context.Context) (map[int]AppliedMigration, error) {
+	iter := d.session.Query(fmt.Sprintf(`SELECT version, name, checksum, applied_at FROM %s.schema_migrations`, d.keyspace)).WithContext(ctx).Iter()
+
+	applied := make(map[int]AppliedMigration)
+	var a AppliedMigration
+	for iter.Scan(&a.Version, &a.Name, &a.Checksum, &a.AppliedAt) {
+		applied[a.Version] = a
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// Apply runs the statements of the migration one by one, or its Go function, and records it.
+// CQL has no multi-statement transactions, so migrations should be written to be re-runnable:
+// CREATE statements with IF NOT EXISTS, while an ALTER TABLE ... ADD of a column that already
+// exists is skipped.
+func (d *CQLDriver) Apply(ctx context.Context, migration Migration) error {
+	if migration.Func != nil {
+		if err := migration.Func(ctx); err != nil {
+			return err
+		}
+	} else if err := d.exec(ctx, migration); err != nil {
+		return err
+	}
+
+	record := fmt.Sprintf(`INSERT INTO %s.schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)`, d.keyspace)
+	return d.session.Query(record, migration.Version, migration.Name, migration.Checksum, time.Now()).WithContext(ctx).Exec()
+}
+
+func (d *CQLDriver) exec(ctx context.Context, migration Migration) error {
+	statements, err := d.render(migration)
+	if err != nil {
+		return err
+	}
+
+	for _, stmt := range statements {
+		err := d.session.Query(stmt).WithContext(ctx).Exec()
+		if columnExists(stmt, err) {
+			slog.Info("column already exists, skipping statement", "migration", migration.Name, "statement", stmt)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%w\n%s", err, stmt)
+		}
+	}
+	return d.session.AwaitSchemaAgreement(ctx)
+}
+
+var addColumn = regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+\S+\s+ADD\s`)
+
+// columnExists reports whether err is the server rejecting an ALTER TABLE ... ADD because the
+// column exists, as when a migration is re-run after failing past that statement.
+func columnExists(stmt string, err error) bool {
+	var reqErr gocql.RequestError
+	if !errors.As(err, &reqErr) || reqErr.Code() != gocql.ErrCodeInvalid || !addColumn.MatchString(stmt) {
+		return false
+	}
+	message := reqErr.Message()
+	return strings.Contains(message, "conflicts with an existing column") || strings.Contains(message, "already exists")
+}
+
+func (d *CQLDriver) render(migration Migration) ([]string, error) {
+	tmpl, err := template.New(migration.Name).Option("missingkey=error").Parse(migration.Source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse migration: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, struct{ Keyspace string }{Keyspace: d.keyspace}); err != nil {
+		return nil, fmt.Errorf("failed to render migration: %w", err)
+	}
+	return splitStatements(buf.String()), nil
+}
+
+// splitStatements splits a CQL script on semicolons, dropping comment lines.
+func splitStatements(script string) []string {
+	var lines []string
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "--") || strings.HasPrefix(trimmed, "//") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	var statements []string
+	for _, stmt := range strings.Split(strings.Join(lines, "\n"), ";") {
+		if stmt = strings.TrimSpace(stmt); stmt != "" {
+			statements = append(statements, stmt)
+		}
+	}
+	return statements
+}
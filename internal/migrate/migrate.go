@@ -0,0 +1,221 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(cql|sql)$`)
+
+// Migration is a single versioned schema change, written either as a script (Source) or in Go (Func).
+type Migration struct {
+	Version  int
+	Name     string
+	Source   string
+	Checksum string
+	// Func applies a Go migration, for data changes the query language cannot express.
+	// Like CQL scripts, it should be safe to re-run after a partial failure.
+	Func func(ctx context.Context) error
+}
+
+// GoMigration returns a migration applied by fn. Its checksum only covers its name.
+func GoMigration(version int, name string, fn func(ctx context.Context) error) Migration {
+	sum := sha256.Sum256([]byte("go:" + name))
+	return Migration{Version: version, Name: name, Checksum: hex.EncodeToString(sum[:]), Func: fn}
+}
+
+// Merge returns migrations and extra ordered by version, failing if two share a version.
+func Merge(migrations []Migration, extra ...Migration) ([]Migration, error) {
+	merged := append(append([]Migration(nil), migrations...), extra...)
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Version < merged[j].Version })
+	for i := 1; i < len(merged); i++ {
+		if merged[i].Version == merged[i-1].Version {
+			return nil, fmt.Errorf("migrations %q and %q share version %d", merged[i-1].Name, merged[i].Name, merged[i].Version)
+		}
+	}
+	return merged, nil
+}
+
+// AppliedMigration is a migration recorded in the tracking table.
+type AppliedMigration struct {
+	Version   int
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// Status describes whether a migration has been applied to a target.
+type Status struct {
+	Migration Migration
+	Applied   *AppliedMigration
+}
+
+// Pending reports whether the migration still has to be applied.
+func (s Status) Pending() bool {
+	return s.Applied == nil
+}
+
+// Modified reports whether the migration changed after it was applied.
+func (s Status) Modified() bool {
+	return s.Applied != nil && s.Applied.Checksum != s.Migration.Checksum
+}
+
+// Driver applies migrations to a single target (a keyspace or a database) and records them.
+type Driver interface {
+	// Target names what is being migrated, for logs and status output.
+	Target() string
+	// Init creates the tracking tables if they do not exist.
+	Init(ctx context.Context) error
+	// Lock blocks until no other migrator holds the lock for the target, and returns a function releasing it
+	// and a context, derived from ctx, that is canceled if the lock is lost before then.
+	Lock(ctx context.Context) (locked context.Context, unlock func(), err error)
+	Applied(ctx context.Context) (map[int]AppliedMigration, error)
+	Apply(ctx context.Context, migration Migration) error
+}
+
+// Load reads the migrations in dir, ordered by version.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	var migrations []Migration
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %q and %q share version %d", other, entry.Name(), version)
+		}
+		seen[version] = entry.Name()
+
+		source, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %q: %w", entry.Name(), err)
+		}
+
+		sum := sha256.Sum256(source)
+		migrations = append(migrations, Migration{
+			Version:  version,
+			Name:     match[2],
+			Source:   string(source),
+			Checksum: hex.EncodeToString(sum[:]),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator applies a set of migrations to one target.
+type Migrator struct {
+	driver     Driver
+	migrations []Migration
+}
+
+// New returns a Migrator applying migrations through driver.
+func New(driver Driver, migrations []Migration) *Migrator {
+	return &Migrator{driver: driver, migrations: migrations}
+}
+
+// Target names what the migrator migrates.
+func (m *Migrator) Target() string {
+	return m.driver.Target()
+}
+
+// Status returns the state of every known migration.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	if err := m.driver.Init(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create migration tables: %w", err)
+	}
+	return m.status(ctx)
+}
+
+func (m *Migrator) status(ctx context.Context) ([]Status, error) {
+	applied, err := m.driver.Applied(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	statuses := make([]Status, len(m.migrations))
+	for i, migration := range m.migrations {
+		statuses[i] = Status{Migration: migration}
+		if a, ok := applied[migration.Version]; ok {
+			statuses[i].Applied = &a
+		}
+	}
+	return statuses, nil
+}
+
+// Pending returns the migrations that have not been applied yet.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, s := range statuses {
+		if s.Pending() {
+			pending = append(pending, s.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies every pending migration in version order while holding the target's lock,
+// and returns the migrations it applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	if err := m.driver.Init(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create migration tables: %w", err)
+	}
+
+	ctx, unlock, err := m.driver.Lock(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer unlock()
+
+	// Read the state only once the lock is held, another deployment may just have migrated.
+	statuses, err := m.status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, s := range statuses {
+		if s.Modified() {
+			slog.Warn("applied migration has been modified", "target", m.Target(), "version", s.Migration.Version, "name", s.Migration.Name)
+		}
+		if !s.Pending() {
+			continue
+		}
+
+		slog.Info("applying migration", "target", m.Target(), "version", s.Migration.Version, "name", s.Migration.Name)
+		if err := m.driver.Apply(ctx, s.Migration); err != nil {
+			if cause := context.Cause(ctx); cause != nil {
+				err = cause
+			}
+			return applied, fmt.Errorf("migration %d_%s failed: %w", s.Migration.Version, s.Migration.Name, err)
+		}
+		applied = append(applied, s.Migration)
+	}
+	return applied, nil
+}
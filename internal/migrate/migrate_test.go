@@ -0,0 +1,172 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gocql/gocql"
+)
+
+// fakeDriver records applied migrations in memory. lose, when set, is called with the
+// cancel function of the lock context once the lock is taken.
+type fakeDriver struct {
+	applied map[int]AppliedMigration
+	lose    func(cancel context.CancelCauseFunc)
+	locked  bool
+}
+
+func (d *fakeDriver) Target() string                 { return "fake" }
+func (d *fakeDriver) Init(ctx context.Context) error { return nil }
+
+func (d *fakeDriver) Lock(ctx context.Context) (context.Context, func(), error) {
+	d.locked = true
+	locked, cancel := context.WithCancelCause(ctx)
+	if d.lose != nil {
+		d.lose(cancel)
+	}
+	return locked, func() { d.locked = false; cancel(nil) }, nil
+}
+
+func (d *fakeDriver) Applied(ctx context.Context) (map[int]AppliedMigration, error) {
+	return d.applied, nil
+}
+
+func (d *fakeDriver) Apply(ctx context.Context, migration Migration) error {
+	if migration.Func != nil {
+		if err := migration.Func(ctx); err != nil {
+			return err
+		}
+	}
+	d.applied[migration.Version] = AppliedMigration{Version: migration.Version, Name: migration.Name, Checksum: migration.Checksum}
+	return nil
+}
+
+func versions(migrations []Migration) []int {
+	var v []int
+	for _, m := range migrations {
+		v = append(v, m.Version)
+	}
+	return v
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		want    []int
+		wantErr bool
+	}{
+		{"ordered", fstest.MapFS{
+			"m/0002_b.cql": {Data: []byte("b")},
+			"m/0001_a.cql": {Data: []byte("a")},
+			"m/0010_c.sql": {Data: []byte("c")},
+		}, []int{1, 2, 10}, false},
+		{"bad name", fstest.MapFS{"m/1-a.cql": {Data: []byte("a")}}, nil, true},
+		{"shared version", fstest.MapFS{
+			"m/0001_a.cql": {Data: []byte("a")},
+			"m/001_b.cql":  {Data: []byte("b")},
+		}, nil, true},
+	}
+	for _, tt := range tests {
+		migrations, err := Load(tt.files, "m")
+		if (err != nil) != tt.wantErr || !slices.Equal(versions(migrations), tt.want) {
+			t.Errorf("%s: Load() = %v, %v; want %v, error %v", tt.name, versions(migrations), err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	fn := func(ctx context.Context) error { return nil }
+	merged, err := Merge([]Migration{{Version: 1}, {Version: 4}}, GoMigration(3, "go", fn))
+	if err != nil || !slices.Equal(versions(merged), []int{1, 3, 4}) {
+		t.Errorf("Merge() = %v, %v", versions(merged), err)
+	}
+	if _, err := Merge([]Migration{{Version: 3}}, GoMigration(3, "go", fn)); err == nil {
+		t.Error("Merge() accepted two migrations of version 3")
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := "-- comment\nCREATE TABLE a (id int);\n// other comment\nALTER TABLE a ADD b text;\n\n"
+	want := []string{"CREATE TABLE a (id int)", "ALTER TABLE a ADD b text"}
+	if got := splitStatements(script); !slices.Equal(got, want) {
+		t.Errorf("splitStatements() = %q, want %q", got, want)
+	}
+}
+
+// requestError is a CQL error of the server.
+type requestError struct {
+	code    int
+	message string
+}
+
+func (e requestError) Code() int       { return e.code }
+func (e requestError) Message() string { return e.message }
+func (e requestError) Error() string   { return e.message }
+
+func TestColumnExists(t *testing.T) {
+	exists := requestError{gocql.ErrCodeInvalid, "Invalid column name request_id because it conflicts with an existing column"}
+	tests := []struct {
+		name string
+		stmt string
+		err  error
+		want bool
+	}{
+		{"existing column", "ALTER TABLE ks.products_outbox ADD request_id text", exists, true},
+		{"existing columns", "alter table ks.products\n  add (price_amount decimal, currency text)", exists, true},
+		{"success", "ALTER TABLE ks.products_outbox ADD request_id text", nil, false},
+		{"other statement", "CREATE TABLE ks.products (id bigint PRIMARY KEY)", exists, false},
+		{"dropped column", "ALTER TABLE ks.products DROP price", exists, false},
+		{"other error", "ALTER TABLE ks.products_outbox ADD request_id text", requestError{gocql.ErrCodeInvalid, "Unknown type foo"}, false},
+		{"not a server error", "ALTER TABLE ks.products_outbox ADD request_id text", errors.New("already exists"), false},
+	}
+	for _, tt := range tests {
+		if got := columnExists(tt.stmt, tt.err); got != tt.want {
+			t.Errorf("%s: columnExists() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUp(t *testing.T) {
+	driver := &fakeDriver{applied: map[int]AppliedMigration{1: {Version: 1}}}
+	m := New(driver, []Migration{{Version: 1, Name: "a"}, {Version: 2, Name: "b"}, {Version: 3, Name: "c"}})
+
+	applied, err := m.Up(context.Background())
+	if err != nil || !slices.Equal(versions(applied), []int{2, 3}) {
+		t.Fatalf("Up() = %v, %v; want [2 3]", versions(applied), err)
+	}
+	if driver.locked {
+		t.Error("Up() did not release the lock")
+	}
+	if pending, _ := m.Pending(context.Background()); len(pending) != 0 {
+		t.Errorf("Pending() = %v after Up()", versions(pending))
+	}
+}
+
+func TestUpAbortsWhenTheLockIsLost(t *testing.T) {
+	var loseLock context.CancelCauseFunc
+	driver := &fakeDriver{
+		applied: map[int]AppliedMigration{},
+		lose:    func(cancel context.CancelCauseFunc) { loseLock = cancel },
+	}
+	backfill := GoMigration(2, "backfill", func(ctx context.Context) error {
+		loseLock(errLockLost)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	m := New(driver, []Migration{{Version: 1, Name: "a"}, backfill, {Version: 3, Name: "c"}})
+
+	applied, err := m.Up(context.Background())
+	if !errors.Is(err, errLockLost) {
+		t.Fatalf("Up() error = %v, want errLockLost", err)
+	}
+	if !slices.Equal(versions(applied), []int{1}) {
+		t.Errorf("Up() applied %v, want [1]", versions(applied))
+	}
+	if _, ok := driver.applied[3]; ok {
+		t.Error("migration 3 ran after the lock was lost")
+	}
+}
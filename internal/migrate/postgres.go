@@ -0,0 +1,96 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// advisoryLockKey identifies the migration lock among PostgreSQL advisory locks.
+const advisoryLockKey = 7_402_913_551
+
+// PostgresDriver migrates a PostgreSQL database. Each migration runs in its own transaction,
+// and concurrent migrators are serialised with a session-level advisory lock.
+type PostgresDriver struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresDriver returns a Driver migrating the database behind pool.
+func NewPostgresDriver(pool *pgxpool.Pool) *PostgresDriver {
+	return &PostgresDriver{pool: pool}
+}
+
+func (d *PostgresDriver) Target() string {
+	return "database " + d.pool.Config().ConnConfig.Database
+}
+
+func (d *PostgresDriver) Init(ctx context.Context) error {
+	_, err := d.pool.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version integer PRIMARY KEY,
+		name text NOT NULL,
+		checksum text NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`)
+	return err
+}
+
+func (d *PostgresDriver) Lock(ctx context.Context) (context.Context, func(), error) {
+	// Advisory locks belong to a connection, so hold one until the lock is released.
+	conn, err := d.pool.Acquire(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, advisoryLockKey); err != nil {
+		conn.Release()
+		return nil, nil, err
+	}
+
+	return ctx, func() {
+		_, _ = conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, advisoryLockKey)
+		conn.Release()
+	}, nil
+}
+
+func (d *PostgresDriver) Applied(ctx context.Context) (map[int]AppliedMigration, error) {
+	rows, err := d.pool.Query(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]AppliedMigration)
+	for rows.Next() {
+		var a AppliedMigration
+		if err := rows.Scan(&a.Version, &a.Name, &a.Checksum, &a.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied[a.Version] = a
+	}
+	return applied, rows.Err()
+}
+
+// Apply runs a script migration and records it in one transaction. Go migrations run
+// outside of it, before being recorded.
+func (d *PostgresDriver) Apply(ctx context.Context, migration Migration) error {
+	if migration.Func != nil {
+		if err := migration.Func(ctx); err != nil {
+			return err
+		}
+	}
+
+	return pgx.BeginFunc(ctx, d.pool, func(tx pgx.Tx) error {
+		if migration.Func == nil {
+			if _, err := tx.Exec(ctx, migration.Source); err != nil {
+				return err
+			}
+		}
+		_, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+			migration.Version, migration.Name, migration.Checksum)
+		if err != nil {
+			return fmt.Errorf("failed to record migration: %w", err)
+		}
+		return nil
+	})
+}
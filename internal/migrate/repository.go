@@ -0,0 +1,59 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/repository"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/migrations"
+)
+
+// ForRepository returns a Migrator for every target behind repo: one per keyspace
+// for CQL backends, and one for the database for PostgreSQL.
+func ForRepository(repo repository.Repository) ([]*Migrator, error) {
+	switch r := repo.(type) {
+	case *repository.CassandraRepository:
+		cqlMigrations, err := Load(migrations.FS, "cassandra")
+		if err != nil {
+			return nil, err
+		}
+		var migrators []*Migrator
+		for _, keyspace := range r.KeyspaceNames() {
+			keyspaceMigrations, err := Merge(cqlMigrations,
+				GoMigration(3, "backfill_price_amount", backfillPriceAmount(r.Session(), keyspace)),
+				GoMigration(6, "backfill_product_categories", backfillProductCategories(r.Session(), keyspace)),
+			)
+			if err != nil {
+				return nil, err
+			}
+			migrators = append(migrators, New(NewCQLDriver(r.Session(), keyspace), keyspaceMigrations))
+		}
+		return migrators, nil
+
+	case *repository.PostgresRepository:
+		sqlMigrations, err := Load(migrations.FS, "postgres")
+		if err != nil {
+			return nil, err
+		}
+		return []*Migrator{New(NewPostgresDriver(r.Pool()), sqlMigrations)}, nil
+
+	default:
+		return nil, fmt.Errorf("migrations are not supported for %T", repo)
+	}
+}
+
+// CheckCurrent returns an error listing the pending migrations of every migrator, if any.
+func CheckCurrent(ctx context.Context, migrators []*Migrator) error {
+	var pendingCount int
+	for _, m := range migrators {
+		pending, err := m.Pending(ctx)
+		if err != nil {
+			return fmt.Errorf("%s: %w", m.Target(), err)
+		}
+		pendingCount += len(pending)
+	}
+	if pendingCount > 0 {
+		return fmt.Errorf("%d pending migrations, run `go run ./cmd/migrate up`", pendingCount)
+	}
+	return nil
+}
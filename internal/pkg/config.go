@@ -1,17 +1,82 @@
 package pkg
 
 import (
+	"errors"
 	"io"
 	"log/slog"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	GrpcServer    GrpcServer    `yaml:"grpc_server"`
-	GraphqlServer GraphqlServer `yaml:"graphql_server"`
-	Database      Database      `yaml:"database"`
-	Queue         Pulsar        `yaml:"queue"`
+	GrpcServer     GrpcServer     `yaml:"grpc_server"`
+	GraphqlServer  GraphqlServer  `yaml:"graphql_server"`
+	Database       Database       `yaml:"database"`
+	Queue          Pulsar         `yaml:"queue"`
+	Auth           Auth           `yaml:"auth"`
+	APIKeys        APIKeys        `yaml:"api_keys"`
+	ProductService ProductService `yaml:"product_service"` // how the gateway connects to the grpc server
+}
+
+type ProductService struct {
+	Target        string                   `yaml:"target"`         // e.g. dns:///products:50051, localhost on grpc_server.port by default
+	Addresses     []string                 `yaml:"addresses"`      // static list of host:port, used instead of target
+	LoadBalancing string                   `yaml:"load_balancing"` // round_robin (default) or pick_first
+	Keepalive     ClientKeepalive          `yaml:"keepalive"`
+	Retry         Retry                    `yaml:"retry"`    // retry policy of read RPCs
+	Timeout       time.Duration            `yaml:"timeout"`  // default deadline of unary RPCs, 10s by default
+	Timeouts      map[string]time.Duration `yaml:"timeouts"` // per method, e.g. ListProducts
+	TLS           TLS                      `yaml:"tls"`
+}
+
+func (s ProductService) validate() error {
+	// Connections to a static list are named after all of it, which no certificate carries.
+	if s.TLS.Enabled && len(s.Addresses) > 1 && s.TLS.ServerName == "" {
+		return errors.New("product_service.tls.server_name is required with several product_service.addresses")
+	}
+	return nil
+}
+
+type ClientKeepalive struct {
+	Time                time.Duration `yaml:"time"` // ping after this long without activity, 0 disables keepalive
+	Timeout             time.Duration `yaml:"timeout"`
+	PermitWithoutStream bool          `yaml:"permit_without_stream"`
+}
+
+type Retry struct {
+	MaxAttempts          int           `yaml:"max_attempts"` // including the first one, at most 5; below 2 disables retries
+	InitialBackoff       time.Duration `yaml:"initial_backoff"`
+	MaxBackoff           time.Duration `yaml:"max_backoff"`
+	BackoffMultiplier    float64       `yaml:"backoff_multiplier"`
+	RetryableStatusCodes []string      `yaml:"retryable_status_codes"` // UNAVAILABLE by default
+}
+
+// APIKeys configures the rate limits of partner API keys.
+type APIKeys struct {
+	RequestsPerSecond float64       `yaml:"requests_per_second"` // default of issued keys
+	Burst             int32         `yaml:"burst"`               // default of issued keys
+	CacheTTL          time.Duration `yaml:"cache_ttl"`           // how long revoked keys may still be accepted
+	RPCsPerRequest    float64       `yaml:"rpcs_per_request"`    // scales key limits on the grpc server, which counts RPCs, 1 when unset
+}
+
+// validate leaves the defaults of issued keys to the AdminService, the only place they are
+// used, so binaries that do not issue keys need no api_keys block.
+func (k APIKeys) validate() error {
+	if k.RPCsPerRequest < 0 {
+		return errors.New("api_keys.rpcs_per_request must not be negative")
+	}
+	return nil
+}
+
+// Auth configures the JWT validation shared by the gateway and the gRPC server.
+type Auth struct {
+	JWKS       string        `yaml:"jwks"`    // file path or http(s) URL of the JWKS, empty disables authentication
+	Refresh    time.Duration `yaml:"refresh"` // how often the JWKS is reloaded
+	Issuer     string        `yaml:"issuer"`
+	Audience   string        `yaml:"audience"`
+	RolesClaim string        `yaml:"roles_claim"` // claim listing the caller's roles, roles by default
+	Leeway     time.Duration `yaml:"leeway"`      // clock skew tolerated on exp and nbf
 }
 
 type Pulsar struct {
@@ -19,15 +84,124 @@ type Pulsar struct {
 	Topic string `yaml:"topic"`
 }
 type Database struct {
-	Username string `yaml:"username"`
-	Path     string `yaml:"path"`
+	Driver          string               `yaml:"driver"` // astra (default), cassandra or postgres
+	Username        string               `yaml:"username"`
+	Path            string               `yaml:"path"`
+	Keyspace        string               `yaml:"keyspace"`         // default CQL keyspace
+	TenantKeyspaces map[string]string    `yaml:"tenant_keyspaces"` // tenant (X-Tenant-ID) -> CQL keyspace
+	Cassandra       Cassandra            `yaml:"cassandra"`
+	Postgres        Postgres             `yaml:"postgres"`
+	Migrations      Migrations           `yaml:"migrations"`
+	Statements      map[string]Statement `yaml:"statements"` // per CQL operation, e.g. list_products
+}
+
+// Statement overrides how a CQL operation is executed; unset fields keep the defaults.
+type Statement struct {
+	Consistency       string          `yaml:"consistency"`        // e.g. LOCAL_QUORUM, LOCAL_ONE
+	SerialConsistency string          `yaml:"serial_consistency"` // SERIAL or LOCAL_SERIAL
+	Idempotent        *bool           `yaml:"idempotent"`
+	Retry             *StatementRetry `yaml:"retry"`
+}
+
+type StatementRetry struct {
+	NumRetries int           `yaml:"num_retries"`
+	MinBackoff time.Duration `yaml:"min_backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+}
+
+type Migrations struct {
+	// RequireCurrent makes the gRPC server refuse to start while migrations are pending.
+	RequireCurrent bool `yaml:"require_current"`
 }
+
+type Cassandra struct {
+	Hosts           []string     `yaml:"hosts"`
+	Port            int          `yaml:"port"`
+	Username        string       `yaml:"username"`
+	LocalDatacenter string       `yaml:"local_datacenter"`
+	ProtocolVersion int          `yaml:"protocol_version"`
+	TLS             CassandraTLS `yaml:"tls"`
+}
+
+type CassandraTLS struct {
+	Enabled            bool   `yaml:"enabled"`
+	CAFile             string `yaml:"ca_file"`
+	CertFile           string `yaml:"cert_file"`
+	KeyFile            string `yaml:"key_file"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+type Postgres struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	User     string `yaml:"user"`
+	Database string `yaml:"database"`
+	SSLMode  string `yaml:"sslmode"`
+}
+
+// TLS configures one side of a TLS connection; it is plaintext unless enabled.
+type TLS struct {
+	Enabled  bool   `yaml:"enabled"`
+	CertFile string `yaml:"cert_file"` // required on servers, presented for mTLS by clients
+	KeyFile  string `yaml:"key_file"`
+	// CAFile verifies the peer: on servers it requires client certificates it signed (mTLS),
+	// on clients it replaces the system roots.
+	CAFile      string   `yaml:"ca_file"`
+	ServerName  string   `yaml:"server_name"`  // clients: name verified in the server certificate, the dialed host by default
+	AllowedSANs []string `yaml:"allowed_sans"` // DNS, IP or URI SANs the peer certificate must carry one of, any when empty
+	MinVersion  string   `yaml:"min_version"`  // 1.2 (default) or 1.3
+}
+
 type GrpcServer struct {
 	Port int `yaml:"port"`
+	TLS  TLS `yaml:"tls"`
+	// KeepaliveMinTime is the shortest keepalive interval accepted from clients, 10s by default.
+	KeepaliveMinTime time.Duration `yaml:"keepalive_min_time"`
+	HealthInterval   time.Duration `yaml:"health_interval"` // how often dependencies are checked, 10s by default
+	DrainDelay       time.Duration `yaml:"drain_delay"`     // NOT_SERVING is reported this long before stopping
+	MetricsPort      int           `yaml:"metrics_port"`    // serves Prometheus metrics on /metrics, 0 disables it
+	CursorTTL        time.Duration `yaml:"cursor_ttl"`      // how long paging cursors stay valid, the key is read from CURSOR_SECRET
 }
 
 type GraphqlServer struct {
-	Port string `yaml:"port"`
+	Port     string        `yaml:"port"`
+	TLS      TLS           `yaml:"tls"`
+	Mode     string        `yaml:"mode"`     // development (default) or production
+	Safelist string        `yaml:"safelist"` // persisted query manifest file or directory, enforced in production
+	Limits   GraphqlLimits `yaml:"limits"`
+	Cache    GraphqlCache  `yaml:"cache"`
+}
+
+// GraphqlCache configures where persisted queries are shared between gateway instances.
+type GraphqlCache struct {
+	Store string        `yaml:"store"` // memory (default), cassandra or redis
+	TTL   time.Duration `yaml:"ttl"`
+	Redis Redis         `yaml:"redis"`
+}
+
+type Redis struct {
+	Address string `yaml:"address"` // password is read from REDIS_PASSWORD
+	DB      int    `yaml:"db"`
+	Prefix  string `yaml:"prefix"`
+}
+
+// GraphqlLimits bound the cost of GraphQL operations; zero values disable a limit.
+type GraphqlLimits struct {
+	MaxDepth      int            `yaml:"max_depth"`
+	MaxComplexity int            `yaml:"max_complexity"`
+	Weights       map[string]int `yaml:"weights"` // Type.field -> cost, overriding graph.DefaultWeights
+	Budget        GraphqlBudget  `yaml:"budget"`
+}
+
+// GraphqlBudget is the complexity a client may spend per minute, per API key or client IP.
+type GraphqlBudget struct {
+	PerMinute int            `yaml:"per_minute"`
+	Keys      map[string]int `yaml:"keys"` // API key id -> complexity per minute
+}
+
+// Production reports whether the gateway runs in production mode.
+func (s GraphqlServer) Production() bool {
+	return s.Mode == "production"
 }
 
 func (c *Config) LoadConfig(file io.Reader) error {
@@ -41,5 +215,13 @@ func (c *Config) LoadConfig(file io.Reader) error {
 		slog.Error("failed to unmarshal yaml", "error", err)
 		return err
 	}
+	if err := c.validate(); err != nil {
+		slog.Error("invalid config", "error", err)
+		return err
+	}
 	return nil
 }
+
+func (c *Config) validate() error {
+	return errors.Join(c.ProductService.validate(), c.APIKeys.validate())
+}
@@ -0,0 +1,57 @@
+package pkg
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	file, err := os.Open("../../config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var cfg Config
+	if err := cfg.LoadConfig(file); err != nil {
+		t.Fatalf("config.yaml is invalid: %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() Config {
+		return Config{}
+	}
+	tests := []struct {
+		name    string
+		modify  func(cfg *Config)
+		wantErr string
+	}{
+		{"valid", func(cfg *Config) {}, ""},
+		{"rpcs per request", func(cfg *Config) { cfg.APIKeys.RPCsPerRequest = 10 }, ""},
+		{"key defaults", func(cfg *Config) { cfg.APIKeys = APIKeys{RequestsPerSecond: 10, Burst: 20} }, ""},
+		{"negative rpcs per request", func(cfg *Config) { cfg.APIKeys.RPCsPerRequest = -1 }, "api_keys.rpcs_per_request"},
+		{"several addresses without TLS", func(cfg *Config) { cfg.ProductService.Addresses = []string{"a:1", "b:1"} }, ""},
+		{"one address with TLS", func(cfg *Config) {
+			cfg.ProductService.Addresses = []string{"a:1"}
+			cfg.ProductService.TLS.Enabled = true
+		}, ""},
+		{"several addresses with TLS", func(cfg *Config) {
+			cfg.ProductService.Addresses = []string{"a:1", "b:1"}
+			cfg.ProductService.TLS.Enabled = true
+		}, "product_service.tls.server_name"},
+		{"several addresses with TLS and a server name", func(cfg *Config) {
+			cfg.ProductService.Addresses = []string{"a:1", "b:1"}
+			cfg.ProductService.TLS = TLS{Enabled: true, ServerName: "products.internal"}
+		}, ""},
+	}
+	for _, tt := range tests {
+		cfg := valid()
+		tt.modify(&cfg)
+		err := cfg.validate()
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: validate() = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
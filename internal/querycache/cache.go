@@ -0,0 +1,93 @@
+package querycache
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var lookups = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "graphql_cache_lookups_total",
+	Help: "Lookups in the gateway caches, by cache, tier (local or shared) and result (hit or miss).",
+}, []string{"cache", "tier", "result"})
+
+// Cache is a graphql.Cache checking an in-process LRU, then the shared store if there is one.
+// Entries found in the store are kept in the LRU. Store keys are prefixed with the cache name.
+type Cache struct {
+	name  string
+	local *lru.LRU[string]
+	store Store
+}
+
+var _ graphql.Cache[string] = &Cache{}
+
+// New returns a Cache of size local entries named name in metrics; store may be nil.
+func New(name string, size int, store Store) *Cache {
+	return &Cache{name: name, local: lru.New[string](size), store: store}
+}
+
+func (c *Cache) Get(ctx context.Context, key string) (string, bool) {
+	if value, ok := c.local.Get(ctx, key); ok {
+		lookups.WithLabelValues(c.name, "local", "hit").Inc()
+		return value, true
+	}
+	lookups.WithLabelValues(c.name, "local", "miss").Inc()
+	if c.store == nil {
+		return "", false
+	}
+
+	value, ok, err := c.store.Get(ctx, c.name+":"+key)
+	if err != nil {
+		slog.Warn("failed to read shared cache", "cache", c.name, "error", err)
+		return "", false
+	}
+	if !ok {
+		lookups.WithLabelValues(c.name, "shared", "miss").Inc()
+		return "", false
+	}
+	lookups.WithLabelValues(c.name, "shared", "hit").Inc()
+	c.local.Add(ctx, key, value)
+	return value, true
+}
+
+func (c *Cache) Add(ctx context.Context, key string, value string) {
+	c.local.Add(ctx, key, value)
+	if c.store == nil {
+		return
+	}
+	if err := c.store.Set(ctx, c.name+":"+key, value); err != nil {
+		slog.Warn("failed to write shared cache", "cache", c.name, "error", err)
+	}
+}
+
+// Local is an instrumented in-process graphql.Cache. Parsed queries use it rather than a shared
+// Cache: their documents point into the schema and must be parsed and validated on every instance.
+type Local[T any] struct {
+	name string
+	lru  *lru.LRU[T]
+}
+
+var _ graphql.Cache[any] = &Local[any]{}
+
+// NewLocal returns a Local of size entries named name in metrics.
+func NewLocal[T any](name string, size int) *Local[T] {
+	return &Local[T]{name: name, lru: lru.New[T](size)}
+}
+
+func (c *Local[T]) Get(ctx context.Context, key string) (T, bool) {
+	value, ok := c.lru.Get(ctx, key)
+	result := "miss"
+	if ok {
+		result = "hit"
+	}
+	lookups.WithLabelValues(c.name, "local", result).Inc()
+	return value, ok
+}
+
+func (c *Local[T]) Add(ctx context.Context, key string, value T) {
+	c.lru.Add(ctx, key, value)
+}
@@ -0,0 +1,86 @@
+// Package querycache implements the gqlgen caches of the gateway: an in-process LRU, optionally
+// in front of a store shared by every gateway instance, with hit and miss metrics.
+package querycache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/redis/go-redis/v9"
+)
+
+// Store is a key-value store shared between gateway instances. Entries expire after the TTL
+// the store was created with.
+type Store interface {
+	Get(ctx context.Context, key string) (value string, ok bool, err error)
+	Set(ctx context.Context, key, value string) error
+}
+
+const (
+	getEntryQuery = `SELECT value FROM %s.query_cache WHERE key = ?`
+	setEntryQuery = `INSERT INTO %s.query_cache (key, value) VALUES (?, ?) USING TTL ?`
+)
+
+// CassandraStore keeps entries in the query_cache table of a keyspace.
+type CassandraStore struct {
+	session  *gocql.Session
+	getQuery string
+	setQuery string
+	ttl      int
+}
+
+// NewCassandraStore returns a Store in keyspace, whose entries expire after ttl.
+func NewCassandraStore(session *gocql.Session, keyspace string, ttl time.Duration) *CassandraStore {
+	return &CassandraStore{
+		session:  session,
+		getQuery: fmt.Sprintf(getEntryQuery, keyspace),
+		setQuery: fmt.Sprintf(setEntryQuery, keyspace),
+		ttl:      int(ttl.Seconds()),
+	}
+}
+
+func (s *CassandraStore) Get(ctx context.Context, key string) (string, bool, error) {
+	var value string
+	err := s.session.Query(s.getQuery, key).WithContext(ctx).Consistency(gocql.LocalOne).Scan(&value)
+	if errors.Is(err, gocql.ErrNotFound) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+func (s *CassandraStore) Set(ctx context.Context, key, value string) error {
+	return s.session.Query(s.setQuery, key, value, s.ttl).WithContext(ctx).Consistency(gocql.LocalOne).Idempotent(true).Exec()
+}
+
+// RedisStore keeps entries in a Redis-compatible server, under a key prefix.
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+	ttl    time.Duration
+}
+
+// NewRedisStore returns a Store in client, whose entries expire after ttl.
+func NewRedisStore(client redis.UniversalClient, prefix string, ttl time.Duration) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix, ttl: ttl}
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) (string, bool, error) {
+	value, err := s.client.Get(ctx, s.prefix+key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+func (s *RedisStore) Set(ctx context.Context, key, value string) error {
+	return s.client.Set(ctx, s.prefix+key, value, s.ttl).Err()
+}
@@ -13,6 +13,7 @@ type PulsarMethods interface {
 	CreatePulsarConnection(ctx context.Context) (pulsar.Client, error)
 	CreatePulsarProducer(ctx context.Context, client pulsar.Client) (pulsar.Producer, error)
 	CreatePulsarConsumer(ctx context.Context, client pulsar.Client, consumerTopic string) (pulsar.Consumer, error)
+	CreatePulsarReader(ctx context.Context, client pulsar.Client, startMessageID pulsar.MessageID) (pulsar.Reader, error)
 }
 
 // PulsarConfig holds the configuration for the Pulsar connection
@@ -81,3 +82,46 @@ func (c *PulsarConfig) CreatePulsarConsumer(ctx context.Context, client pulsar.C
 
 	return consumer, nil
 }
+
+// readerQueueSize keeps readers from prefetching far ahead of what their caller consumes.
+const readerQueueSize = 16
+
+// CreatePulsarReader creates a reader on the topic positioned after startMessageID
+func (c *PulsarConfig) CreatePulsarReader(ctx context.Context, client pulsar.Client, startMessageID pulsar.MessageID) (pulsar.Reader, error) {
+	readerOptions := pulsar.ReaderOptions{
+		Topic:             c.TopicName,
+		StartMessageID:    startMessageID,
+		ReceiverQueueSize: readerQueueSize,
+	}
+
+	reader, err := client.CreateReader(readerOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Pulsar reader: %w", err)
+	}
+
+	return reader, nil
+}
+
+// PingProducer reports whether producer can publish: it is open and its broker answers
+// topic lookups.
+func PingProducer(ctx context.Context, client pulsar.Client, producer pulsar.Producer) error {
+	if err := producer.FlushWithCtx(ctx); err != nil {
+		return fmt.Errorf("failed to flush producer: %w", err)
+	}
+
+	// Lookups take no context, so an unresponsive broker is abandoned at the deadline of ctx.
+	lookup := make(chan error, 1)
+	go func() {
+		_, err := client.TopicPartitions(producer.Topic())
+		lookup <- err
+	}()
+	select {
+	case err := <-lookup:
+		if err != nil {
+			return fmt.Errorf("failed to look up topic %s: %w", producer.Topic(), err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to look up topic %s: %w", producer.Topic(), ctx.Err())
+	}
+}
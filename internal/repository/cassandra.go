@@ -0,0 +1,486 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/tenant"
+	"golang.org/x/sync/errgroup"
+	"gopkg.in/inf.v0"
+)
+
+// Statement templates, %s being the keyspace. They are executed through the Registry.
+const (
+	createCategoryQuery = `INSERT INTO %s.categories(id, name, description, created_at) VALUES(?, ?, ?, ?)`
+	getCategoryQuery    = `SELECT id, name, description, created_at FROM %s.categories WHERE id = ?`
+
+	insertProductQuery = `INSERT INTO %s.products
+		(id, name, description, price_amount, currency, stock, category_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	setProductQuery = `UPDATE %s.products
+		SET name = ?, description = ?, price_amount = ?, currency = ?, stock = ?, updated_at = ?
+		WHERE category_id = ? AND id = ?`
+	getProductQuery   = `SELECT id, name, description, price_amount, currency, stock, category_id, created_at, updated_at FROM %s.products WHERE category_id = ? AND id = ?`
+	listProductsQuery = `
+		SELECT id, name, description, price_amount, currency, stock, created_at, updated_at
+		FROM %s.products
+		WHERE category_id = ?`
+	listProductsAfterQuery = `
+		SELECT id, name, description, price_amount, currency, stock, created_at, updated_at
+		FROM %s.products
+		WHERE category_id = ? AND id < ?`
+	countProductsQuery = `SELECT COUNT(*) FROM %s.products WHERE category_id = ?`
+
+	// product_categories finds the partition of a product from its id alone.
+	insertProductCategoryQuery = `INSERT INTO %s.product_categories (id, category_id) VALUES (?, ?)`
+	getProductCategoryQuery    = `SELECT category_id FROM %s.product_categories WHERE id = ?`
+
+	saveInventoryQuery = `INSERT INTO %s.inventory
+              (product_id, category_id, stock_count, created_at, last_updated_at)
+              VALUES (?, ?, ?, ?, ?)`
+
+	insertOutboxQuery = `INSERT INTO %s.products_outbox
+		(id, bucket, payload, event_type, request_id)
+		VALUES (?, ?, ?, ?, ?)`
+	fetchOutboxQuery = `
+		SELECT id, payload, event_type, request_id
+		FROM %s.products_outbox
+		WHERE bucket = ?
+		ORDER BY id ASC
+		LIMIT ?`
+	deleteOutboxQuery = `DELETE FROM %s.products_outbox WHERE bucket = ? AND id = ?`
+	countOutboxQuery  = `SELECT COUNT(*), MIN(id) FROM %s.products_outbox WHERE bucket = ?`
+
+	insertAPIKeyQuery = `INSERT INTO %s.api_keys
+		(id, secret_hash, name, requests_per_second, burst, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`
+	getAPIKeyQuery    = `SELECT id, secret_hash, name, requests_per_second, burst, created_at, revoked_at FROM %s.api_keys WHERE id = ?`
+	revokeAPIKeyQuery = `UPDATE %s.api_keys SET revoked_at = ? WHERE id = ? IF EXISTS`
+)
+
+// getCategoriesConcurrency bounds the queries GetCategories and GetProducts run at once.
+const getCategoriesConcurrency = 16
+
+// CassandraRepository implements Repository on top of a CQL session (Astra DB, Cassandra or ScyllaDB).
+// Each tenant's data lives in its own keyspace, chosen from the tenant in the request context.
+type CassandraRepository struct {
+	session   *gocql.Session
+	keyspaces Keyspaces
+	registry  *Registry
+}
+
+// NewCassandraRepository returns a Repository backed by the given session. Operations missing
+// from options run with DefaultStatementOptions.
+func NewCassandraRepository(session *gocql.Session, keyspaces Keyspaces, options map[Operation]StatementOptions) (*CassandraRepository, error) {
+	if err := keyspaces.Validate(); err != nil {
+		return nil, err
+	}
+
+	repo := &CassandraRepository{session: session, keyspaces: keyspaces}
+	repo.registry = NewRegistry(session, repo.KeyspaceNames(), options)
+	return repo, nil
+}
+
+// Prepare prepares and validates every statement of every keyspace.
+func (r *CassandraRepository) Prepare(ctx context.Context) error {
+	return r.registry.Prepare(ctx)
+}
+
+// Session returns the underlying CQL session.
+func (r *CassandraRepository) Session() *gocql.Session {
+	return r.session
+}
+
+// KeyspaceNames returns every keyspace the repository reads from, the default one first.
+func (r *CassandraRepository) KeyspaceNames() []string {
+	var names []string
+	for _, ks := range r.keyspaces.all() {
+		names = append(names, ks.keyspace)
+	}
+	return names
+}
+
+func (r *CassandraRepository) CreateCategory(ctx context.Context, category *Category) error {
+	keyspace, err := r.keyspaces.For(ctx)
+	if err != nil {
+		return err
+	}
+	return r.registry.Query(ctx, keyspace, OpCreateCategory, category.ID, category.Name, category.Description, category.CreatedAt).Exec()
+}
+
+func (r *CassandraRepository) GetCategory(ctx context.Context, id int64) (*Category, error) {
+	keyspace, err := r.keyspaces.For(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var category Category
+	if err := r.registry.Query(ctx, keyspace, OpGetCategory, id).Scan(&category.ID, &category.Name, &category.Description, &category.CreatedAt); err != nil {
+		if err == gocql.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &category, nil
+}
+
+// GetCategories reads each category's partition concurrently rather than with a multi-partition IN query.
+func (r *CassandraRepository) GetCategories(ctx context.Context, ids []int64) ([]*Category, error) {
+	keyspace, err := r.keyspaces.For(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make([]*Category, len(ids))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(getCategoriesConcurrency)
+	for i, id := range ids {
+		g.Go(func() error {
+			var category Category
+			if err := r.registry.Query(gctx, keyspace, OpGetCategory, id).Scan(&category.ID, &category.Name, &category.Description, &category.CreatedAt); err != nil {
+				if err == gocql.ErrNotFound {
+					return nil
+				}
+				return err
+			}
+			found[i] = &category
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	categories := make([]*Category, 0, len(found))
+	for _, category := range found {
+		if category != nil {
+			categories = append(categories, category)
+		}
+	}
+	return categories, nil
+}
+
+// CreateProduct writes the product, its category lookup and its outbox event in a single logged batch.
+func (r *CassandraRepository) CreateProduct(ctx context.Context, product *Product, event *OutboxEvent) error {
+	keyspace, err := r.keyspaces.For(ctx)
+	if err != nil {
+		return err
+	}
+
+	outboxID := gocql.TimeUUID()
+	event.ID = outboxID.String()
+
+	batch := r.registry.Batch(ctx, OpCreateProduct)
+	r.registry.AddToBatch(batch, keyspace, OpInsertProduct,
+		product.ID, product.Name, product.Description, product.Price.decimal(), product.Price.Currency, product.Stock, product.CategoryID, product.CreatedAt, product.UpdatedAt,
+	)
+	r.registry.AddToBatch(batch, keyspace, OpInsertProductCategory, product.ID, product.CategoryID)
+	r.registry.AddToBatch(batch, keyspace, OpInsertOutbox, outboxID, bucketFor(product.CreatedAt), event.Payload, event.EventType, event.RequestID)
+
+	return r.session.ExecuteBatch(batch)
+}
+
+// UpdateProduct writes the product and its outbox event in a single logged batch. The batch spans
+// two tables, so it cannot be conditional: callers check that the product exists beforehand.
+func (r *CassandraRepository) UpdateProduct(ctx context.Context, product *Product, event *OutboxEvent) error {
+	keyspace, err := r.keyspaces.For(ctx)
+	if err != nil {
+		return err
+	}
+
+	outboxID := gocql.TimeUUID()
+	event.ID = outboxID.String()
+
+	batch := r.registry.Batch(ctx, OpUpdateProduct)
+	r.registry.AddToBatch(batch, keyspace, OpSetProduct,
+		product.Name, product.Description, product.Price.decimal(), product.Price.Currency, product.Stock, product.UpdatedAt, product.CategoryID, product.ID,
+	)
+	r.registry.AddToBatch(batch, keyspace, OpInsertOutbox, outboxID, bucketFor(product.UpdatedAt), event.Payload, event.EventType, event.RequestID)
+
+	return r.session.ExecuteBatch(batch)
+}
+
+func (r *CassandraRepository) GetProduct(ctx context.Context, categoryID, productID int64) (*Product, error) {
+	keyspace, err := r.keyspaces.For(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		product  Product
+		amount   *inf.Dec
+		currency string
+	)
+	err = r.registry.Query(ctx, keyspace, OpGetProduct, categoryID, productID).Scan(
+		&product.ID, &product.Name, &product.Description, &amount, &currency, &product.Stock, &product.CategoryID, &product.CreatedAt, &product.UpdatedAt,
+	)
+	if err != nil {
+		if err == gocql.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if product.Price, err = moneyFromCQL(amount, currency); err != nil {
+		return nil, fmt.Errorf("invalid price of product %d: %w", product.ID, err)
+	}
+	return &product, nil
+}
+
+// GetProducts looks up the category of each product in product_categories, then reads the
+// products concurrently like GetCategories.
+func (r *CassandraRepository) GetProducts(ctx context.Context, ids []int64) ([]*Product, error) {
+	keyspace, err := r.keyspaces.For(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make([]*Product, len(ids))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(getCategoriesConcurrency)
+	for i, id := range ids {
+		g.Go(func() error {
+			var categoryID int64
+			if err := r.registry.Query(gctx, keyspace, OpGetProductCategory, id).Scan(&categoryID); err != nil {
+				if err == gocql.ErrNotFound {
+					return nil
+				}
+				return err
+			}
+			product, err := r.GetProduct(gctx, categoryID, id)
+			if err != nil {
+				if errors.Is(err, ErrNotFound) {
+					return nil
+				}
+				return err
+			}
+			found[i] = product
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	products := make([]*Product, 0, len(found))
+	for _, product := range found {
+		if product != nil {
+			products = append(products, product)
+		}
+	}
+	return products, nil
+}
+
+// ListProducts resumes from afterID rather than a paging state, which is only valid for the
+// statement that produced it. The paging state still tells whether another page may follow.
+func (r *CassandraRepository) ListProducts(ctx context.Context, categoryID, afterID int64, pageSize int) (*ProductPage, error) {
+	keyspace, err := r.keyspaces.For(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := r.registry.Query(ctx, keyspace, OpListProducts, categoryID)
+	if afterID != 0 {
+		query = r.registry.Query(ctx, keyspace, OpListProductsAfter, categoryID, afterID)
+	}
+	query = query.PageSize(pageSize)
+
+	iter := query.Iter()
+	defer iter.Close()
+
+	var products []*Product
+	for {
+		var (
+			product  = Product{CategoryID: categoryID}
+			amount   *inf.Dec
+			currency string
+		)
+		if !iter.Scan(&product.ID, &product.Name, &product.Description, &amount, &currency, &product.Stock, &product.CreatedAt, &product.UpdatedAt) {
+			break
+		}
+		if product.Price, err = moneyFromCQL(amount, currency); err != nil {
+			return nil, fmt.Errorf("invalid price of product %d: %w", product.ID, err)
+		}
+		products = append(products, &product)
+	}
+
+	hasMore := len(iter.PageState()) > 0
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+
+	return &ProductPage{Products: products, HasMore: hasMore}, nil
+}
+
+// CountProducts counts the category's partition, at LOCAL_ONE unless configured otherwise.
+func (r *CassandraRepository) CountProducts(ctx context.Context, categoryID int64) (int64, error) {
+	keyspace, err := r.keyspaces.For(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	if err := r.registry.Query(ctx, keyspace, OpCountProducts, categoryID).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *CassandraRepository) SaveInventory(ctx context.Context, inventory *Inventory) error {
+	keyspace, err := r.keyspaces.For(ctx)
+	if err != nil {
+		return err
+	}
+	return r.registry.Query(ctx, keyspace, OpSaveInventory, inventory.ProductID, inventory.CategoryID, inventory.StockCount, inventory.CreatedAt, time.Now()).Exec()
+}
+
+// CreateAPIKey stores the key in the default keyspace, API keys being shared by every tenant.
+func (r *CassandraRepository) CreateAPIKey(ctx context.Context, key *APIKey) error {
+	return r.registry.Query(ctx, r.keyspaces.Default, OpInsertAPIKey,
+		key.ID, key.SecretHash, key.Name, key.RequestsPerSecond, key.Burst, key.CreatedAt,
+	).Exec()
+}
+
+func (r *CassandraRepository) GetAPIKey(ctx context.Context, id string) (*APIKey, error) {
+	var key APIKey
+	err := r.registry.Query(ctx, r.keyspaces.Default, OpGetAPIKey, id).Scan(
+		&key.ID, &key.SecretHash, &key.Name, &key.RequestsPerSecond, &key.Burst, &key.CreatedAt, &key.RevokedAt,
+	)
+	if err != nil {
+		if err == gocql.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// RevokeAPIKey is conditional on the key existing, as a plain UPDATE would insert a partial row.
+func (r *CassandraRepository) RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) error {
+	applied, err := r.registry.Query(ctx, r.keyspaces.Default, OpRevokeAPIKey, revokedAt, id).MapScanCAS(map[string]interface{}{})
+	if err != nil {
+		return err
+	}
+	if !applied {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RelayOutbox publishes the events in the recent outbox buckets of every keyspace, oldest first,
+// and deletes the ones that were published. The context passed to publish carries the tenant owning the event.
+func (r *CassandraRepository) RelayOutbox(ctx context.Context, limit int, publish PublishFunc) error {
+	buckets := outboxBuckets(time.Now())
+
+	for _, ks := range r.keyspaces.all() {
+		remaining := limit
+		for _, bucket := range buckets {
+			fetched, err := r.relayKeyspace(tenant.NewContext(ctx, ks.tenantID), ks.keyspace, bucket, remaining, publish)
+			if err != nil {
+				return fmt.Errorf("keyspace %s: %w", ks.keyspace, err)
+			}
+			if remaining -= fetched; remaining <= 0 {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// OutboxBacklog counts the events in the recent outbox buckets of every keyspace, the ones RelayOutbox reads.
+func (r *CassandraRepository) OutboxBacklog(ctx context.Context) (*OutboxBacklog, error) {
+	buckets := outboxBuckets(time.Now())
+
+	backlog := &OutboxBacklog{}
+	for _, ks := range r.keyspaces.all() {
+		for _, bucket := range buckets {
+			var (
+				count  int64
+				oldest gocql.UUID
+			)
+			if err := r.registry.Query(ctx, ks.keyspace, OpCountOutbox, bucket).Scan(&count, &oldest); err != nil {
+				return nil, fmt.Errorf("keyspace %s: failed to count outbox events: %w", ks.keyspace, err)
+			}
+			backlog.Events += count
+			if count > 0 && (backlog.Oldest.IsZero() || oldest.Time().Before(backlog.Oldest)) {
+				backlog.Oldest = oldest.Time()
+			}
+		}
+	}
+	return backlog, nil
+}
+
+// relayKeyspace publishes up to limit events of one bucket and returns how many it fetched.
+func (r *CassandraRepository) relayKeyspace(ctx context.Context, keyspace, bucket string, limit int, publish PublishFunc) (int, error) {
+	iter := r.registry.Query(ctx, keyspace, OpFetchOutbox, bucket, limit).Iter()
+	var events []*OutboxEvent
+	for {
+		var (
+			id      gocql.UUID
+			payload string
+			event   OutboxEvent
+		)
+		if !iter.Scan(&id, &payload, &event.EventType, &event.RequestID) {
+			break
+		}
+		event.ID = id.String()
+		event.Payload = []byte(payload)
+		event.CreatedAt = id.Time()
+		events = append(events, &event)
+	}
+	if err := iter.Close(); err != nil {
+		return 0, fmt.Errorf("failed to fetch outbox events: %w", err)
+	}
+
+	for _, event := range events {
+		if err := publish(ctx, event); err != nil {
+			slog.Error("Failed to publish outbox event", "error", err, "messageID", event.ID)
+			continue
+		}
+
+		id, err := gocql.ParseUUID(event.ID)
+		if err != nil {
+			return len(events), fmt.Errorf("invalid outbox event id %q: %w", event.ID, err)
+		}
+		slog.Info("Deleting message", "messageID", event.ID)
+		if err := r.registry.Query(ctx, keyspace, OpDeleteOutbox, bucket, id).Exec(); err != nil {
+			return len(events), fmt.Errorf("failed to delete outbox event: %w", err)
+		}
+	}
+	return len(events), nil
+}
+
+func (r *CassandraRepository) Ping(ctx context.Context) error {
+	if r.session.Closed() {
+		return errors.New("session is closed")
+	}
+	var version string
+	return r.session.Query("SELECT release_version FROM system.local").
+		WithContext(ctx).
+		Consistency(gocql.One).
+		Scan(&version)
+}
+
+func (r *CassandraRepository) Close() {
+	r.session.Close()
+}
+
+func bucketFor(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// outboxDays is how many daily outbox buckets are relayed, up to today's.
+const outboxDays = 7
+
+// outboxBuckets returns the buckets of the last outboxDays days, oldest first, so events written
+// just before midnight or left behind while the queue was down are still relayed.
+func outboxBuckets(now time.Time) []string {
+	buckets := make([]string, outboxDays)
+	for i := range buckets {
+		buckets[i] = bucketFor(now.AddDate(0, 0, i-outboxDays+1))
+	}
+	return buckets
+}
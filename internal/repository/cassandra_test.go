@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestOutboxBuckets(t *testing.T) {
+	now := time.Date(2025, 3, 2, 0, 5, 0, 0, time.UTC)
+	want := []string{"2025-02-24", "2025-02-25", "2025-02-26", "2025-02-27", "2025-02-28", "2025-03-01", "2025-03-02"}
+	got := outboxBuckets(now)
+	if !slices.Equal(got, want) {
+		t.Errorf("outboxBuckets() = %v, want %v", got, want)
+	}
+	// An event written just before midnight must still be relayed the next day.
+	if written := bucketFor(now.Add(-10 * time.Minute)); !slices.Contains(got, written) {
+		t.Errorf("bucket %s of an event from yesterday is not relayed", written)
+	}
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/tenant"
+)
+
+// DefaultKeyspace is used when no keyspace is configured.
+const DefaultKeyspace = "products_keyspace_v2"
+
+var keyspaceName = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]{0,47}$`)
+
+// Keyspaces maps tenants to the CQL keyspace holding their data.
+type Keyspaces struct {
+	Default string
+	Tenants map[string]string
+}
+
+// Validate checks that every keyspace is a valid CQL identifier, since keyspaces are spliced into queries.
+func (k Keyspaces) Validate() error {
+	if !keyspaceName.MatchString(k.Default) {
+		return fmt.Errorf("invalid keyspace %q", k.Default)
+	}
+	for tenantID, keyspace := range k.Tenants {
+		if !keyspaceName.MatchString(keyspace) {
+			return fmt.Errorf("invalid keyspace %q for tenant %q", keyspace, tenantID)
+		}
+	}
+	return nil
+}
+
+// Known reports whether tenantID has a keyspace of its own.
+func (k Keyspaces) Known(tenantID string) bool {
+	_, ok := k.Tenants[tenantID]
+	return ok
+}
+
+// For returns the keyspace of the tenant in ctx, or the default keyspace when there is none.
+func (k Keyspaces) For(ctx context.Context) (string, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return k.Default, nil
+	}
+	keyspace, ok := k.Tenants[tenantID]
+	if !ok {
+		return "", fmt.Errorf("unknown tenant %q", tenantID)
+	}
+	return keyspace, nil
+}
+
+// keyspaceTenant is a keyspace and the tenant used to address it; the tenant is empty for the default keyspace.
+type keyspaceTenant struct {
+	keyspace string
+	tenantID string
+}
+
+// all returns every distinct keyspace, the default one first.
+func (k Keyspaces) all() []keyspaceTenant {
+	result := []keyspaceTenant{{keyspace: k.Default}}
+	seen := map[string]bool{k.Default: true}
+
+	tenantIDs := make([]string, 0, len(k.Tenants))
+	for tenantID := range k.Tenants {
+		tenantIDs = append(tenantIDs, tenantID)
+	}
+	sort.Strings(tenantIDs)
+
+	for _, tenantID := range tenantIDs {
+		keyspace := k.Tenants[tenantID]
+		if seen[keyspace] {
+			continue
+		}
+		seen[keyspace] = true
+		result = append(result, keyspaceTenant{keyspace: keyspace, tenantID: tenantID})
+	}
+	return result
+}
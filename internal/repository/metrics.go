@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/gocql/gocql"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var cqlDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "cassandra_query_duration_seconds",
+	Help:    "Latency of each attempt of the CQL operations in the Registry, by operation and result (ok or error).",
+	Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+}, []string{"operation", "result"})
+
+// observer records the latency of the queries and batches of an operation.
+type observer Operation
+
+func (o observer) ObserveQuery(_ context.Context, q gocql.ObservedQuery) {
+	o.observe(q.End.Sub(q.Start).Seconds(), q.Err)
+}
+
+func (o observer) ObserveBatch(_ context.Context, b gocql.ObservedBatch) {
+	o.observe(b.End.Sub(b.Start).Seconds(), b.Err)
+}
+
+func (o observer) observe(seconds float64, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	cqlDuration.WithLabelValues(string(o), result).Observe(seconds)
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"gopkg.in/inf.v0"
+)
+
+// nanosScale is the number of fractional digits Money keeps.
+const nanosScale = 9
+
+var nanosPerUnit = big.NewInt(1_000_000_000)
+
+var errMoneyRange = errors.New("amount does not fit in money")
+
+// Money is an exact amount in a currency: Units whole units plus Nanos billionths of a unit,
+// with the same sign, as in google.type.Money.
+type Money struct {
+	Units    int64
+	Nanos    int32
+	Currency string
+}
+
+// unscaled returns the amount in billionths of a unit.
+func (m Money) unscaled() *big.Int {
+	n := new(big.Int).Mul(big.NewInt(m.Units), nanosPerUnit)
+	return n.Add(n, big.NewInt(int64(m.Nanos)))
+}
+
+// moneyFromDecimal returns the money worth unscaled * 10^-scale, dropping digits past the nanos.
+func moneyFromDecimal(unscaled *big.Int, scale int, currency string) (Money, error) {
+	n := new(big.Int).Set(unscaled)
+	if scale < nanosScale {
+		n.Mul(n, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(nanosScale-scale)), nil))
+	} else if scale > nanosScale {
+		n.Quo(n, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale-nanosScale)), nil))
+	}
+
+	units, nanos := new(big.Int).QuoRem(n, nanosPerUnit, new(big.Int))
+	if !units.IsInt64() {
+		return Money{}, errMoneyRange
+	}
+	return Money{Units: units.Int64(), Nanos: int32(nanos.Int64()), Currency: currency}, nil
+}
+
+// decimal returns the amount as a CQL decimal, without trailing zeros.
+func (m Money) decimal() *inf.Dec {
+	n, scale := m.unscaled(), inf.Scale(nanosScale)
+	ten, rem := big.NewInt(10), new(big.Int)
+	for scale > 0 {
+		q, r := new(big.Int).QuoRem(n, ten, rem)
+		if r.Sign() != 0 {
+			break
+		}
+		n, scale = q, scale-1
+	}
+	return inf.NewDecBig(n, scale)
+}
+
+func moneyFromCQL(d *inf.Dec, currency string) (Money, error) {
+	if d == nil {
+		return Money{Currency: currency}, nil
+	}
+	return moneyFromDecimal(d.UnscaledBig(), int(d.Scale()), currency)
+}
+
+// numeric returns the amount as a PostgreSQL numeric.
+func (m Money) numeric() pgtype.Numeric {
+	return pgtype.Numeric{Int: m.unscaled(), Exp: -nanosScale, Valid: true}
+}
+
+func moneyFromNumeric(n pgtype.Numeric, currency string) (Money, error) {
+	if !n.Valid || n.Int == nil {
+		return Money{Currency: currency}, nil
+	}
+	return moneyFromDecimal(n.Int, -int(n.Exp), currency)
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"gopkg.in/inf.v0"
+)
+
+func TestMoneyFromDecimal(t *testing.T) {
+	tests := []struct {
+		name     string
+		unscaled int64
+		scale    int
+		want     Money
+		wantErr  bool
+	}{
+		{"whole", 19, 0, Money{Units: 19}, false},
+		{"cents", 1999, 2, Money{Units: 19, Nanos: 990_000_000}, false},
+		{"nanos", 1_000_000_001, 9, Money{Units: 1, Nanos: 1}, false},
+		{"beyond nanos are dropped", 12_345_678_901, 10, Money{Units: 1, Nanos: 234_567_890}, false},
+		{"negative", -1999, 2, Money{Units: -19, Nanos: -990_000_000}, false},
+		{"negative below one", -5, 1, Money{Nanos: -500_000_000}, false},
+		{"negative scale", 12, -3, Money{Units: 12_000}, false},
+		{"zero", 0, 2, Money{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := moneyFromDecimal(big.NewInt(tt.unscaled), tt.scale, "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("moneyFromDecimal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("moneyFromDecimal() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyFromDecimalOutOfRange(t *testing.T) {
+	huge := new(big.Int).Exp(big.NewInt(10), big.NewInt(20), nil)
+	if _, err := moneyFromDecimal(huge, 0, "USD"); err != errMoneyRange {
+		t.Errorf("moneyFromDecimal(1e20) error = %v, want errMoneyRange", err)
+	}
+}
+
+func TestMoneyDecimal(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{Money{Units: 19, Nanos: 990_000_000}, "19.99"},
+		{Money{Units: 19}, "19"},
+		{Money{Units: 0, Nanos: 1}, "0.000000001"},
+		{Money{Units: -19, Nanos: -990_000_000}, "-19.99"},
+		{Money{}, "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.money.decimal().String(); got != tt.want {
+				t.Errorf("decimal() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyRoundTrips(t *testing.T) {
+	amounts := []Money{
+		{Units: 19, Nanos: 990_000_000, Currency: "USD"},
+		{Units: -3, Nanos: -1, Currency: "EUR"},
+		{Units: 9_223_372_036, Nanos: 854_775_807, Currency: "JPY"},
+		{Currency: "USD"},
+	}
+	for _, m := range amounts {
+		cql, err := moneyFromCQL(m.decimal(), m.Currency)
+		if err != nil || cql != m {
+			t.Errorf("CQL round trip of %+v = %+v, %v", m, cql, err)
+		}
+		pg, err := moneyFromNumeric(m.numeric(), m.Currency)
+		if err != nil || pg != m {
+			t.Errorf("numeric round trip of %+v = %+v, %v", m, pg, err)
+		}
+	}
+}
+
+func TestMoneyFromNull(t *testing.T) {
+	if got, err := moneyFromCQL((*inf.Dec)(nil), "USD"); err != nil || got != (Money{Currency: "USD"}) {
+		t.Errorf("moneyFromCQL(nil) = %+v, %v", got, err)
+	}
+	if got, err := moneyFromNumeric(pgtype.Numeric{}, "USD"); err != nil || got != (Money{Currency: "USD"}) {
+		t.Errorf("moneyFromNumeric(NULL) = %+v, %v", got, err)
+	}
+}
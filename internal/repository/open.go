@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/database"
+	"github.com/yaninyzwitty/gqlgen-proxy-grpc-products-service/internal/pkg"
+)
+
+const (
+	DriverAstra     = "astra"
+	DriverCassandra = "cassandra"
+	DriverPostgres  = "postgres"
+)
+
+// Open connects to the storage backend selected by cfg.Driver and returns a Repository for it.
+// Secrets are read from the DATABASE_TOKEN, CASSANDRA_PASSWORD and POSTGRES_PASSWORD environment variables.
+func Open(ctx context.Context, cfg pkg.Database, timeout time.Duration) (Repository, error) {
+	switch cfg.Driver {
+	case "", DriverAstra:
+		astraCfg := &database.AstraConfig{
+			Username: cfg.Username,
+			Path:     cfg.Path,
+			Token:    os.Getenv("DATABASE_TOKEN"),
+		}
+		session, err := database.NewAstraDB().Connect(ctx, astraCfg, timeout)
+		if err != nil {
+			return nil, err
+		}
+		return newCassandraRepository(session, cfg)
+
+	case DriverCassandra:
+		cassandraCfg := &database.CassandraConfig{
+			Hosts:           cfg.Cassandra.Hosts,
+			Port:            cfg.Cassandra.Port,
+			Username:        cfg.Cassandra.Username,
+			Password:        os.Getenv("CASSANDRA_PASSWORD"),
+			LocalDatacenter: cfg.Cassandra.LocalDatacenter,
+			ProtocolVersion: cfg.Cassandra.ProtocolVersion,
+			TLS: database.CassandraTLSConfig{
+				Enabled:            cfg.Cassandra.TLS.Enabled,
+				CAFile:             cfg.Cassandra.TLS.CAFile,
+				CertFile:           cfg.Cassandra.TLS.CertFile,
+				KeyFile:            cfg.Cassandra.TLS.KeyFile,
+				InsecureSkipVerify: cfg.Cassandra.TLS.InsecureSkipVerify,
+			},
+		}
+		session, err := database.NewCassandraDB().Connect(ctx, cassandraCfg, timeout)
+		if err != nil {
+			return nil, err
+		}
+		return newCassandraRepository(session, cfg)
+
+	case DriverPostgres:
+		postgresCfg := &database.PostgresConfig{
+			Host:     cfg.Postgres.Host,
+			Port:     cfg.Postgres.Port,
+			User:     cfg.Postgres.User,
+			Password: os.Getenv("POSTGRES_PASSWORD"),
+			Database: cfg.Postgres.Database,
+			SSLMode:  cfg.Postgres.SSLMode,
+		}
+		pool, err := database.NewPostgresDB().Connect(ctx, postgresCfg, timeout)
+		if err != nil {
+			return nil, err
+		}
+		return NewPostgresRepository(pool), nil
+
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", cfg.Driver)
+	}
+}
+
+func newCassandraRepository(session *gocql.Session, cfg pkg.Database) (Repository, error) {
+	keyspaces := Keyspaces{Default: cfg.Keyspace, Tenants: cfg.TenantKeyspaces}
+	if keyspaces.Default == "" {
+		keyspaces.Default = DefaultKeyspace
+	}
+
+	options, err := statementOptions(cfg.Statements)
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+
+	repo, err := NewCassandraRepository(session, keyspaces, options)
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	return repo, nil
+}
+
+// statementOptions applies the configured overrides on top of DefaultStatementOptions.
+func statementOptions(statements map[string]pkg.Statement) (map[Operation]StatementOptions, error) {
+	options := DefaultStatementOptions()
+	for name, stmt := range statements {
+		op := Operation(name)
+		if !KnownOperation(op) {
+			return nil, fmt.Errorf("unknown statement %q in database.statements", name)
+		}
+
+		opts := options[op]
+		if stmt.Consistency != "" {
+			consistency, err := gocql.ParseConsistencyWrapper(stmt.Consistency)
+			if err != nil {
+				return nil, fmt.Errorf("statement %q: %w", name, err)
+			}
+			opts.Consistency = consistency
+		}
+		if stmt.SerialConsistency != "" {
+			if err := opts.SerialConsistency.UnmarshalText([]byte(stmt.SerialConsistency)); err != nil {
+				return nil, fmt.Errorf("statement %q: %w", name, err)
+			}
+		}
+		if stmt.Idempotent != nil {
+			opts.Idempotent = *stmt.Idempotent
+		}
+		if stmt.Retry != nil {
+			opts.RetryPolicy = &gocql.ExponentialBackoffRetryPolicy{
+				NumRetries: stmt.Retry.NumRetries,
+				Min:        stmt.Retry.MinBackoff,
+				Max:        stmt.Retry.MaxBackoff,
+			}
+		}
+		options[op] = opts
+	}
+	return options, nil
+}
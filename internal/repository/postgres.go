@@ -0,0 +1,392 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	pgCreateCategoryQuery = `INSERT INTO categories (id, name, description, created_at) VALUES ($1, $2, $3, $4)`
+	pgGetCategoryQuery    = `SELECT id, name, description, created_at FROM categories WHERE id = $1`
+	pgGetCategoriesQuery  = `SELECT id, name, description, created_at FROM categories WHERE id = ANY($1)`
+
+	pgInsertProductQuery = `INSERT INTO products
+		(id, name, description, price_amount, currency, stock, category_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	pgUpdateProductQuery = `UPDATE products
+		SET name = $1, description = $2, price_amount = $3, currency = $4, stock = $5, updated_at = $6
+		WHERE category_id = $7 AND id = $8`
+	pgGetProductQuery   = `SELECT id, name, description, price_amount, currency, stock, category_id, created_at, updated_at FROM products WHERE category_id = $1 AND id = $2`
+	pgGetProductsQuery  = `SELECT id, name, description, price_amount, currency, stock, category_id, created_at, updated_at FROM products WHERE id = ANY($1)`
+	pgListProductsQuery = `
+		SELECT id, name, description, price_amount, currency, stock, created_at, updated_at
+		FROM products
+		WHERE category_id = $1 AND ($2::bigint IS NULL OR id < $2)
+		ORDER BY id DESC
+		LIMIT $3`
+	pgCountProductsQuery = `SELECT count(*) FROM products WHERE category_id = $1`
+
+	pgSaveInventoryQuery = `INSERT INTO inventory
+		(product_id, category_id, stock_count, created_at, last_updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (product_id, category_id)
+		DO UPDATE SET stock_count = EXCLUDED.stock_count, last_updated_at = EXCLUDED.last_updated_at`
+
+	pgInsertOutboxQuery = `INSERT INTO products_outbox (id, payload, event_type, request_id) VALUES ($1, $2, $3, $4)`
+	pgFetchOutboxQuery  = `
+		SELECT id, payload, event_type, COALESCE(request_id, ''), created_at
+		FROM products_outbox
+		ORDER BY created_at ASC
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED`
+	pgDeleteOutboxQuery = `DELETE FROM products_outbox WHERE id = $1`
+	pgCountOutboxQuery  = `SELECT count(*), min(created_at) FROM products_outbox`
+
+	pgInsertAPIKeyQuery = `INSERT INTO api_keys
+		(id, secret_hash, name, requests_per_second, burst, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+	pgGetAPIKeyQuery    = `SELECT id, secret_hash, name, requests_per_second, burst, created_at, revoked_at FROM api_keys WHERE id = $1`
+	pgRevokeAPIKeyQuery = `UPDATE api_keys SET revoked_at = COALESCE(revoked_at, $1) WHERE id = $2`
+)
+
+// pgStatements lists every query with the number of parameters and result columns the repository uses.
+var pgStatements = []struct {
+	name    string
+	sql     string
+	args    int
+	columns int
+}{
+	{"create_category", pgCreateCategoryQuery, 4, 0},
+	{"get_category", pgGetCategoryQuery, 1, 4},
+	{"get_categories", pgGetCategoriesQuery, 1, 4},
+	{"insert_product", pgInsertProductQuery, 9, 0},
+	{"update_product", pgUpdateProductQuery, 8, 0},
+	{"get_product", pgGetProductQuery, 2, 9},
+	{"get_products", pgGetProductsQuery, 1, 9},
+	{"list_products", pgListProductsQuery, 3, 8},
+	{"count_products", pgCountProductsQuery, 1, 1},
+	{"save_inventory", pgSaveInventoryQuery, 5, 0},
+	{"insert_outbox", pgInsertOutboxQuery, 4, 0},
+	{"fetch_outbox", pgFetchOutboxQuery, 1, 5},
+	{"delete_outbox", pgDeleteOutboxQuery, 1, 0},
+	{"count_outbox", pgCountOutboxQuery, 0, 2},
+	{"insert_api_key", pgInsertAPIKeyQuery, 6, 0},
+	{"get_api_key", pgGetAPIKeyQuery, 1, 7},
+	{"revoke_api_key", pgRevokeAPIKeyQuery, 2, 0},
+}
+
+// PostgresRepository implements Repository on top of a PostgreSQL connection pool.
+type PostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRepository returns a Repository backed by the given pool.
+func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
+	return &PostgresRepository{pool: pool}
+}
+
+// Pool returns the underlying connection pool.
+func (r *PostgresRepository) Pool() *pgxpool.Pool {
+	return r.pool
+}
+
+func (r *PostgresRepository) CreateCategory(ctx context.Context, category *Category) error {
+	_, err := r.pool.Exec(ctx, pgCreateCategoryQuery, category.ID, category.Name, category.Description, category.CreatedAt)
+	return err
+}
+
+func (r *PostgresRepository) GetCategory(ctx context.Context, id int64) (*Category, error) {
+	var category Category
+	if err := r.pool.QueryRow(ctx, pgGetCategoryQuery, id).Scan(&category.ID, &category.Name, &category.Description, &category.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &category, nil
+}
+
+func (r *PostgresRepository) GetCategories(ctx context.Context, ids []int64) ([]*Category, error) {
+	rows, err := r.pool.Query(ctx, pgGetCategoriesQuery, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []*Category
+	for rows.Next() {
+		var category Category
+		if err := rows.Scan(&category.ID, &category.Name, &category.Description, &category.CreatedAt); err != nil {
+			return nil, err
+		}
+		categories = append(categories, &category)
+	}
+	return categories, rows.Err()
+}
+
+// CreateProduct writes the product and its outbox event in a single transaction.
+func (r *PostgresRepository) CreateProduct(ctx context.Context, product *Product, event *OutboxEvent) error {
+	event.ID = gocql.TimeUUID().String()
+
+	return pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, pgInsertProductQuery,
+			product.ID, product.Name, product.Description, product.Price.numeric(), product.Price.Currency, product.Stock, product.CategoryID, product.CreatedAt, product.UpdatedAt,
+		); err != nil {
+			return fmt.Errorf("failed to insert product: %w", err)
+		}
+		if _, err := tx.Exec(ctx, pgInsertOutboxQuery, event.ID, string(event.Payload), event.EventType, event.RequestID); err != nil {
+			return fmt.Errorf("failed to insert outbox event: %w", err)
+		}
+		return nil
+	})
+}
+
+// UpdateProduct updates the product and writes its outbox event in a single transaction.
+func (r *PostgresRepository) UpdateProduct(ctx context.Context, product *Product, event *OutboxEvent) error {
+	event.ID = gocql.TimeUUID().String()
+
+	return pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, pgUpdateProductQuery,
+			product.Name, product.Description, product.Price.numeric(), product.Price.Currency, product.Stock, product.UpdatedAt, product.CategoryID, product.ID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update product: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+		if _, err := tx.Exec(ctx, pgInsertOutboxQuery, event.ID, string(event.Payload), event.EventType, event.RequestID); err != nil {
+			return fmt.Errorf("failed to insert outbox event: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *PostgresRepository) GetProduct(ctx context.Context, categoryID, productID int64) (*Product, error) {
+	var (
+		product  Product
+		amount   pgtype.Numeric
+		currency string
+	)
+	err := r.pool.QueryRow(ctx, pgGetProductQuery, categoryID, productID).Scan(
+		&product.ID, &product.Name, &product.Description, &amount, &currency, &product.Stock, &product.CategoryID, &product.CreatedAt, &product.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if product.Price, err = moneyFromNumeric(amount, currency); err != nil {
+		return nil, fmt.Errorf("invalid price of product %d: %w", product.ID, err)
+	}
+	return &product, nil
+}
+
+func (r *PostgresRepository) GetProducts(ctx context.Context, ids []int64) ([]*Product, error) {
+	rows, err := r.pool.Query(ctx, pgGetProductsQuery, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []*Product
+	for rows.Next() {
+		var (
+			product  Product
+			amount   pgtype.Numeric
+			currency string
+		)
+		if err := rows.Scan(
+			&product.ID, &product.Name, &product.Description, &amount, &currency, &product.Stock, &product.CategoryID, &product.CreatedAt, &product.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if product.Price, err = moneyFromNumeric(amount, currency); err != nil {
+			return nil, fmt.Errorf("invalid price of product %d: %w", product.ID, err)
+		}
+		products = append(products, &product)
+	}
+	return products, rows.Err()
+}
+
+// ListProducts pages through a category by keyset, after the last seen product id.
+func (r *PostgresRepository) ListProducts(ctx context.Context, categoryID, afterID int64, pageSize int) (*ProductPage, error) {
+	var after *int64
+	if afterID != 0 {
+		after = &afterID
+	}
+
+	// Fetch one extra row to know whether another page exists.
+	rows, err := r.pool.Query(ctx, pgListProductsQuery, categoryID, after, pageSize+1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []*Product
+	for rows.Next() {
+		var (
+			product  = Product{CategoryID: categoryID}
+			amount   pgtype.Numeric
+			currency string
+		)
+		if err := rows.Scan(&product.ID, &product.Name, &product.Description, &amount, &currency, &product.Stock, &product.CreatedAt, &product.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if product.Price, err = moneyFromNumeric(amount, currency); err != nil {
+			return nil, fmt.Errorf("invalid price of product %d: %w", product.ID, err)
+		}
+		products = append(products, &product)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	page := &ProductPage{Products: products}
+	if len(products) > pageSize {
+		page.Products = products[:pageSize]
+		page.HasMore = true
+	}
+	return page, nil
+}
+
+func (r *PostgresRepository) CountProducts(ctx context.Context, categoryID int64) (int64, error) {
+	var count int64
+	if err := r.pool.QueryRow(ctx, pgCountProductsQuery, categoryID).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *PostgresRepository) SaveInventory(ctx context.Context, inventory *Inventory) error {
+	_, err := r.pool.Exec(ctx, pgSaveInventoryQuery, inventory.ProductID, inventory.CategoryID, inventory.StockCount, inventory.CreatedAt, time.Now())
+	return err
+}
+
+// RelayOutbox locks up to limit outbox rows with SELECT ... FOR UPDATE SKIP LOCKED, so several relays
+// can run side by side, and deletes the ones that were published before committing.
+func (r *PostgresRepository) RelayOutbox(ctx context.Context, limit int, publish PublishFunc) error {
+	return pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, pgFetchOutboxQuery, limit)
+		if err != nil {
+			return fmt.Errorf("failed to fetch outbox events: %w", err)
+		}
+
+		var events []*OutboxEvent
+		for rows.Next() {
+			var (
+				event   OutboxEvent
+				payload string
+			)
+			if err := rows.Scan(&event.ID, &payload, &event.EventType, &event.RequestID, &event.CreatedAt); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan outbox event: %w", err)
+			}
+			event.Payload = []byte(payload)
+			events = append(events, &event)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to fetch outbox events: %w", err)
+		}
+
+		for _, event := range events {
+			if err := publish(ctx, event); err != nil {
+				slog.Error("Failed to publish outbox event", "error", err, "messageID", event.ID)
+				continue
+			}
+
+			slog.Info("Deleting message", "messageID", event.ID)
+			if _, err := tx.Exec(ctx, pgDeleteOutboxQuery, event.ID); err != nil {
+				return fmt.Errorf("failed to delete outbox event: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (r *PostgresRepository) OutboxBacklog(ctx context.Context) (*OutboxBacklog, error) {
+	var (
+		backlog OutboxBacklog
+		oldest  *time.Time
+	)
+	if err := r.pool.QueryRow(ctx, pgCountOutboxQuery).Scan(&backlog.Events, &oldest); err != nil {
+		return nil, fmt.Errorf("failed to count outbox events: %w", err)
+	}
+	if oldest != nil {
+		backlog.Oldest = *oldest
+	}
+	return &backlog, nil
+}
+
+// Prepare describes every query on one connection, failing when it refers to missing tables or
+// columns or does not have the shape the repository expects. pgx prepares and caches the
+// statements on each connection when they are first used.
+func (r *PostgresRepository) Prepare(ctx context.Context) error {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	for _, stmt := range pgStatements {
+		desc, err := conn.Conn().Prepare(ctx, "", stmt.sql)
+		if err != nil {
+			return fmt.Errorf("failed to prepare %s: %w", stmt.name, err)
+		}
+		if len(desc.ParamOIDs) != stmt.args {
+			return fmt.Errorf("statement %s has %d parameters, expected %d", stmt.name, len(desc.ParamOIDs), stmt.args)
+		}
+		if len(desc.Fields) != stmt.columns {
+			return fmt.Errorf("statement %s returns %d columns, expected %d", stmt.name, len(desc.Fields), stmt.columns)
+		}
+	}
+	return nil
+}
+
+func (r *PostgresRepository) Ping(ctx context.Context) error {
+	return r.pool.Ping(ctx)
+}
+
+func (r *PostgresRepository) Close() {
+	r.pool.Close()
+}
+
+func (r *PostgresRepository) CreateAPIKey(ctx context.Context, key *APIKey) error {
+	_, err := r.pool.Exec(ctx, pgInsertAPIKeyQuery, key.ID, key.SecretHash, key.Name, key.RequestsPerSecond, key.Burst, key.CreatedAt)
+	return err
+}
+
+func (r *PostgresRepository) GetAPIKey(ctx context.Context, id string) (*APIKey, error) {
+	var key APIKey
+	err := r.pool.QueryRow(ctx, pgGetAPIKeyQuery, id).Scan(
+		&key.ID, &key.SecretHash, &key.Name, &key.RequestsPerSecond, &key.Burst, &key.CreatedAt, &key.RevokedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *PostgresRepository) RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) error {
+	tag, err := r.pool.Exec(ctx, pgRevokeAPIKeyQuery, revokedAt, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
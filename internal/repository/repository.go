@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned when the requested row does not exist.
+var ErrNotFound = errors.New("not found")
+
+// Category is a stored product category.
+type Category struct {
+	ID          int64
+	Name        string
+	Description string
+	CreatedAt   time.Time
+}
+
+// Product is a stored product.
+type Product struct {
+	ID          int64
+	CategoryID  int64
+	Name        string
+	Description string
+	Price       Money
+	Stock       int32
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// ProductPage is a single page of products. The next one is listed after its last product.
+type ProductPage struct {
+	Products []*Product
+	HasMore  bool // another page may follow
+}
+
+// Inventory is the stock level of a product.
+type Inventory struct {
+	ProductID  int64
+	CategoryID int64
+	StockCount int32
+	CreatedAt  time.Time
+}
+
+// APIKey identifies a partner calling the APIs. Only a hash of its secret is stored.
+type APIKey struct {
+	ID                string
+	SecretHash        []byte
+	Name              string
+	RequestsPerSecond float64
+	Burst             int32
+	CreatedAt         time.Time
+	RevokedAt         *time.Time
+}
+
+// OutboxEvent is an event written in the same transaction as the change it describes
+// and later relayed to the message queue.
+type OutboxEvent struct {
+	ID        string
+	EventType string
+	Payload   []byte
+	RequestID string // of the request that caused the event, empty when unknown
+	CreatedAt time.Time
+}
+
+// OutboxBacklog describes the events waiting in the outbox.
+type OutboxBacklog struct {
+	Events int64
+	Oldest time.Time // zero when there are none
+}
+
+// PublishFunc publishes a single outbox event. The event is removed from the outbox only when it returns nil.
+type PublishFunc func(ctx context.Context, event *OutboxEvent) error
+
+// Repository defines the storage operations used by the product service and the gateway.
+type Repository interface {
+	CreateCategory(ctx context.Context, category *Category) error
+	GetCategory(ctx context.Context, id int64) (*Category, error)
+	// GetCategories returns the categories of ids that exist, in no particular order.
+	GetCategories(ctx context.Context, ids []int64) ([]*Category, error)
+	CreateProduct(ctx context.Context, product *Product, event *OutboxEvent) error
+	// UpdateProduct overwrites the fields of an existing product and writes its outbox event.
+	UpdateProduct(ctx context.Context, product *Product, event *OutboxEvent) error
+	GetProduct(ctx context.Context, categoryID, productID int64) (*Product, error)
+	// GetProducts returns the products of ids that exist, whatever their category, in no particular order.
+	GetProducts(ctx context.Context, ids []int64) ([]*Product, error)
+	// ListProducts returns the products of a category in descending id order, after the product
+	// afterID (0 starts from the first product).
+	ListProducts(ctx context.Context, categoryID, afterID int64, pageSize int) (*ProductPage, error)
+	// CountProducts returns the number of products in a category. It may lag behind concurrent writes.
+	CountProducts(ctx context.Context, categoryID int64) (int64, error)
+	SaveInventory(ctx context.Context, inventory *Inventory) error
+	// API keys are shared by every tenant.
+	CreateAPIKey(ctx context.Context, key *APIKey) error
+	GetAPIKey(ctx context.Context, id string) (*APIKey, error)
+	// RevokeAPIKey returns ErrNotFound when no key has the id.
+	RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) error
+	RelayOutbox(ctx context.Context, limit int, publish PublishFunc) error
+	// OutboxBacklog returns the events RelayOutbox has yet to publish.
+	OutboxBacklog(ctx context.Context) (*OutboxBacklog, error)
+	// Prepare prepares every statement and validates it against the database schema.
+	Prepare(ctx context.Context) error
+	// Ping reports whether the database can currently serve queries.
+	Ping(ctx context.Context) error
+	Close()
+}
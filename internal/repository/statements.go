@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// Operation names a statement, or a batch of statements, in the Registry.
+type Operation string
+
+const (
+	OpCreateCategory        Operation = "create_category"
+	OpGetCategory           Operation = "get_category"
+	OpCreateProduct         Operation = "create_product" // batch of insert_product, insert_product_category and insert_outbox
+	OpInsertProduct         Operation = "insert_product"
+	OpUpdateProduct         Operation = "update_product" // batch of set_product and insert_outbox
+	OpSetProduct            Operation = "set_product"
+	OpInsertProductCategory Operation = "insert_product_category"
+	OpGetProduct            Operation = "get_product"
+	OpGetProductCategory    Operation = "get_product_category"
+	OpListProducts          Operation = "list_products"
+	OpListProductsAfter     Operation = "list_products_after"
+	OpCountProducts         Operation = "count_products"
+	OpSaveInventory         Operation = "save_inventory"
+	OpInsertOutbox          Operation = "insert_outbox"
+	OpFetchOutbox           Operation = "fetch_outbox"
+	OpDeleteOutbox          Operation = "delete_outbox"
+	OpCountOutbox           Operation = "count_outbox"
+	OpInsertAPIKey          Operation = "insert_api_key"
+	OpGetAPIKey             Operation = "get_api_key"
+	OpRevokeAPIKey          Operation = "revoke_api_key"
+)
+
+// statementDef is a CQL statement template, %s being the keyspace, with the shape its callers expect.
+type statementDef struct {
+	cql     string
+	args    int // bind markers
+	columns int // result columns
+}
+
+var statementDefs = map[Operation]statementDef{
+	OpCreateCategory:        {cql: createCategoryQuery, args: 4},
+	OpGetCategory:           {cql: getCategoryQuery, args: 1, columns: 4},
+	OpInsertProduct:         {cql: insertProductQuery, args: 9},
+	OpSetProduct:            {cql: setProductQuery, args: 8},
+	OpInsertProductCategory: {cql: insertProductCategoryQuery, args: 2},
+	OpGetProduct:            {cql: getProductQuery, args: 2, columns: 9},
+	OpGetProductCategory:    {cql: getProductCategoryQuery, args: 1, columns: 1},
+	OpListProducts:          {cql: listProductsQuery, args: 1, columns: 8},
+	OpListProductsAfter:     {cql: listProductsAfterQuery, args: 2, columns: 8},
+	OpCountProducts:         {cql: countProductsQuery, args: 1, columns: 1},
+	OpSaveInventory:         {cql: saveInventoryQuery, args: 5},
+	OpInsertOutbox:          {cql: insertOutboxQuery, args: 5},
+	OpFetchOutbox:           {cql: fetchOutboxQuery, args: 2, columns: 4},
+	OpDeleteOutbox:          {cql: deleteOutboxQuery, args: 2},
+	OpCountOutbox:           {cql: countOutboxQuery, args: 1, columns: 2},
+	OpInsertAPIKey:          {cql: insertAPIKeyQuery, args: 6},
+	OpGetAPIKey:             {cql: getAPIKeyQuery, args: 1, columns: 7},
+	OpRevokeAPIKey:          {cql: revokeAPIKeyQuery, args: 2},
+}
+
+// batchOps are operations that only carry options for a batch of other statements.
+var batchOps = map[Operation]bool{
+	OpCreateProduct: true,
+	OpUpdateProduct: true,
+}
+
+// StatementOptions tunes how an operation is executed.
+type StatementOptions struct {
+	Consistency       gocql.Consistency
+	SerialConsistency gocql.SerialConsistency
+	Idempotent        bool
+	RetryPolicy       gocql.RetryPolicy // nil keeps the cluster default
+}
+
+// DefaultStatementOptions returns the options used for operations that are not configured:
+// LOCAL_QUORUM everywhere except listing and counting products and counting outbox events, which
+// favour latency with LOCAL_ONE.
+// Every statement is a plain insert, update, delete or select, so all of them are safe to retry.
+func DefaultStatementOptions() map[Operation]StatementOptions {
+	options := make(map[Operation]StatementOptions)
+	for op := range statementDefs {
+		options[op] = StatementOptions{Consistency: gocql.LocalQuorum, SerialConsistency: gocql.LocalSerial, Idempotent: true}
+	}
+	for op := range batchOps {
+		options[op] = StatementOptions{Consistency: gocql.LocalQuorum, SerialConsistency: gocql.LocalSerial, Idempotent: true}
+	}
+	for _, op := range []Operation{OpListProducts, OpListProductsAfter, OpCountProducts, OpCountOutbox} {
+		opts := options[op]
+		opts.Consistency = gocql.LocalOne
+		options[op] = opts
+	}
+	return options
+}
+
+// KnownOperation reports whether op can be configured.
+func KnownOperation(op Operation) bool {
+	_, ok := statementDefs[op]
+	return ok || batchOps[op]
+}
+
+// Registry holds every CQL statement of every keyspace, with the options each operation runs with.
+type Registry struct {
+	session    *gocql.Session
+	statements map[string]map[Operation]string
+	options    map[Operation]StatementOptions
+}
+
+// NewRegistry builds the statements of keyspaces. Operations missing from options use DefaultStatementOptions.
+func NewRegistry(session *gocql.Session, keyspaces []string, options map[Operation]StatementOptions) *Registry {
+	merged := DefaultStatementOptions()
+	for op, opts := range options {
+		merged[op] = opts
+	}
+
+	statements := make(map[string]map[Operation]string, len(keyspaces))
+	for _, keyspace := range keyspaces {
+		statements[keyspace] = make(map[Operation]string, len(statementDefs))
+		for op, def := range statementDefs {
+			statements[keyspace][op] = fmt.Sprintf(def.cql, keyspace)
+		}
+	}
+
+	return &Registry{session: session, statements: statements, options: merged}
+}
+
+// errPrepareOnly aborts a bound query once the server has prepared it, before anything is executed.
+var errPrepareOnly = errors.New("prepare only")
+
+// Prepare prepares every statement and checks its bind markers and result columns against
+// what the repository passes and scans. The server rejects statements referring to missing
+// tables or columns, so this fails fast on a schema that does not match the code.
+func (r *Registry) Prepare(ctx context.Context) error {
+	keyspaces := make([]string, 0, len(r.statements))
+	for keyspace := range r.statements {
+		keyspaces = append(keyspaces, keyspace)
+	}
+	sort.Strings(keyspaces)
+
+	start := time.Now()
+	for _, keyspace := range keyspaces {
+		for op, stmt := range r.statements[keyspace] {
+			def := statementDefs[op]
+
+			var info *gocql.QueryInfo
+			err := r.session.Bind(stmt, func(q *gocql.QueryInfo) ([]interface{}, error) {
+				info = q
+				return nil, errPrepareOnly
+			}).WithContext(ctx).Exec()
+			if !errors.Is(err, errPrepareOnly) {
+				return fmt.Errorf("failed to prepare %s in keyspace %s: %w", op, keyspace, err)
+			}
+
+			if len(info.Args) != def.args {
+				return fmt.Errorf("statement %s in keyspace %s has %d bind markers, expected %d", op, keyspace, len(info.Args), def.args)
+			}
+			if len(info.Rval) != def.columns {
+				return fmt.Errorf("statement %s in keyspace %s returns %d columns, expected %d", op, keyspace, len(info.Rval), def.columns)
+			}
+		}
+	}
+
+	slog.Info("CQL statements prepared", "keyspaces", keyspaces, "statements", len(statementDefs), "duration", time.Since(start))
+	return nil
+}
+
+// Query returns the statement of op in keyspace, bound to values and configured with the options of op.
+// Its latency is recorded under the name of op.
+func (r *Registry) Query(ctx context.Context, keyspace string, op Operation, values ...interface{}) *gocql.Query {
+	opts := r.options[op]
+	query := r.session.Query(r.statements[keyspace][op], values...).
+		WithContext(ctx).
+		Consistency(opts.Consistency).
+		SerialConsistency(opts.SerialConsistency).
+		Idempotent(opts.Idempotent).
+		Observer(observer(op))
+	if opts.RetryPolicy != nil {
+		query = query.RetryPolicy(opts.RetryPolicy)
+	}
+	return query
+}
+
+// Batch returns a logged batch configured with the options of the batch operation op.
+func (r *Registry) Batch(ctx context.Context, op Operation) *gocql.Batch {
+	opts := r.options[op]
+	batch := r.session.NewBatch(gocql.LoggedBatch).WithContext(ctx).SerialConsistency(opts.SerialConsistency).Observer(observer(op))
+	batch.SetConsistency(opts.Consistency)
+	if opts.RetryPolicy != nil {
+		batch = batch.RetryPolicy(opts.RetryPolicy)
+	}
+	return batch
+}
+
+// AddToBatch appends the statement of op in keyspace to batch.
+func (r *Registry) AddToBatch(batch *gocql.Batch, keyspace string, op Operation, values ...interface{}) {
+	batch.Entries = append(batch.Entries, gocql.BatchEntry{
+		Stmt:       r.statements[keyspace][op],
+		Args:       values,
+		Idempotent: r.options[op].Idempotent,
+	})
+}
@@ -0,0 +1,77 @@
+// Package safelist restricts the GraphQL gateway to approved operations, listed in persisted
+// query manifests generated from client code by cmd/safelist.
+package safelist
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ManifestFormat identifies manifests in the format of Apollo's persisted query manifests.
+const ManifestFormat = "apollo-persisted-query-manifest"
+
+// Manifest lists approved operations.
+type Manifest struct {
+	Format     string      `json:"format"`
+	Version    int         `json:"version"`
+	Operations []Operation `json:"operations"`
+}
+
+// Operation is an approved operation; ID is the SHA-256 of Body, as sent by APQ clients.
+type Operation struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Body string `json:"body"`
+}
+
+// Hash returns the APQ hash of a query.
+func Hash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadManifests reads the manifest at path, or every *.json manifest when path is a directory,
+// and returns the approved operation bodies by hash.
+func LoadManifests(path string) (map[string]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read safelist: %w", err)
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		if files, err = filepath.Glob(filepath.Join(path, "*.json")); err != nil {
+			return nil, fmt.Errorf("failed to list manifests: %w", err)
+		}
+	}
+
+	operations := make(map[string]string)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest %q: %w", file, err)
+		}
+
+		var manifest Manifest
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return nil, fmt.Errorf("invalid manifest %q: %w", file, err)
+		}
+		if manifest.Format != ManifestFormat || manifest.Version != 1 {
+			return nil, fmt.Errorf("manifest %q is not a version 1 %s", file, ManifestFormat)
+		}
+
+		for _, op := range manifest.Operations {
+			if !strings.EqualFold(op.ID, Hash(op.Body)) {
+				return nil, fmt.Errorf("manifest %q: id of operation %q does not match its body", file, op.Name)
+			}
+			operations[strings.ToLower(op.ID)] = op.Body
+		}
+	}
+	return operations, nil
+}
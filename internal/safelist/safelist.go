@@ -0,0 +1,146 @@
+package safelist
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/fsnotify/fsnotify"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+const errNotSafelisted = "OPERATION_NOT_SAFELISTED"
+
+// reloadDelay groups the bursts of events a manifest update produces into one reload.
+const reloadDelay = 200 * time.Millisecond
+
+// Safelist is a gqlgen extension resolving persisted query hashes from the manifests and,
+// when enforced, rejecting every other operation. Unenforced, unknown operations are logged.
+// It must be added before the AutomaticPersistedQuery extension.
+type Safelist struct {
+	path       string
+	enforce    bool
+	operations atomic.Pointer[map[string]string]
+}
+
+var _ interface {
+	graphql.OperationParameterMutator
+	graphql.HandlerExtension
+} = &Safelist{}
+
+// New loads the manifests at path, a file or a directory of manifests.
+func New(path string, enforce bool) (*Safelist, error) {
+	s := &Safelist{path: path, enforce: enforce}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload reads the manifests again; on error the current operations are kept.
+func (s *Safelist) Reload() error {
+	operations, err := LoadManifests(s.path)
+	if err != nil {
+		return err
+	}
+	s.operations.Store(&operations)
+	slog.Info("loaded safelist", "path", s.path, "operations", len(operations))
+	return nil
+}
+
+// Watch reloads the manifests whenever they change, until ctx is done.
+func (s *Safelist) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	// Watch the directory rather than the file, which editors and Kubernetes replace on update.
+	dir := s.path
+	if info, err := os.Stat(s.path); err == nil && !info.IsDir() {
+		dir = filepath.Dir(s.path)
+	}
+	if err := watcher.Add(dir); err != nil {
+		return err
+	}
+
+	reload := time.NewTimer(0)
+	<-reload.C
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Has(fsnotify.Chmod) {
+				continue
+			}
+			reload.Reset(reloadDelay)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Warn("safelist watcher error", "error", err)
+		case <-reload.C:
+			if err := s.Reload(); err != nil {
+				slog.Error("failed to reload safelist, keeping the previous one", "path", s.path, "error", err)
+			}
+		}
+	}
+}
+
+func (s *Safelist) ExtensionName() string {
+	return "Safelist"
+}
+
+func (s *Safelist) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (s *Safelist) MutateOperationParameters(ctx context.Context, rawParams *graphql.RawParams) *gqlerror.Error {
+	operations := *s.operations.Load()
+
+	if rawParams.Query == "" {
+		// APQ clients may send only the hash of a safelisted operation.
+		hash := persistedQueryHash(rawParams)
+		if body, ok := operations[hash]; ok {
+			rawParams.Query = body
+			return nil
+		}
+		if hash != "" && s.enforce {
+			return notSafelisted(rawParams)
+		}
+		return nil
+	}
+
+	if _, ok := operations[Hash(rawParams.Query)]; ok {
+		return nil
+	}
+	if s.enforce {
+		return notSafelisted(rawParams)
+	}
+	slog.Warn("operation is not in the safelist", "operation", rawParams.OperationName, "hash", Hash(rawParams.Query))
+	return nil
+}
+
+func persistedQueryHash(rawParams *graphql.RawParams) string {
+	persistedQuery, _ := rawParams.Extensions["persistedQuery"].(map[string]any)
+	hash, _ := persistedQuery["sha256Hash"].(string)
+	return strings.ToLower(hash)
+}
+
+func notSafelisted(rawParams *graphql.RawParams) *gqlerror.Error {
+	err := gqlerror.Errorf("operation is not in the safelist")
+	errcode.Set(err, errNotSafelisted)
+	slog.Warn("rejected operation not in the safelist", "operation", rawParams.OperationName)
+	return err
+}
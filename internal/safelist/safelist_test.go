@@ -0,0 +1,165 @@
+package safelist
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+const (
+	getProduct  = "query GetProduct { getProduct(categoryId: 1, productId: 2) { id } }"
+	getCategory = "query GetCategory { getCategory(id: 1) { id } }"
+)
+
+func writeManifest(t *testing.T, path string, manifest Manifest) {
+	t.Helper()
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func manifestOf(bodies ...string) Manifest {
+	manifest := Manifest{Format: ManifestFormat, Version: 1}
+	for _, body := range bodies {
+		manifest.Operations = append(manifest.Operations, Operation{ID: Hash(body), Name: "op", Type: "query", Body: body})
+	}
+	return manifest
+}
+
+func TestHash(t *testing.T) {
+	// echo -n '{ __typename }' | sha256sum
+	if got, want := Hash("{ __typename }"), "7f56e67dd21ab3f30d1ff8b7bed08893f0a0db86449836189b361dd1e56ddb4b"; got != want {
+		t.Errorf("Hash() = %s, want %s", got, want)
+	}
+}
+
+func TestLoadManifests(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest Manifest
+		wantErr  string
+	}{
+		{"valid", manifestOf(getProduct, getCategory), ""},
+		{"upper case id", Manifest{Format: ManifestFormat, Version: 1, Operations: []Operation{{ID: strings.ToUpper(Hash(getProduct)), Body: getProduct}}}, ""},
+		{"wrong format", Manifest{Format: "other", Version: 1}, "is not a version 1"},
+		{"wrong version", Manifest{Format: ManifestFormat, Version: 2}, "is not a version 1"},
+		{"id mismatch", Manifest{Format: ManifestFormat, Version: 1, Operations: []Operation{{ID: Hash(getCategory), Name: "GetProduct", Body: getProduct}}}, "does not match its body"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "manifest.json")
+			writeManifest(t, path, tt.manifest)
+
+			operations, err := LoadManifests(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadManifests() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, op := range tt.manifest.Operations {
+				if operations[Hash(op.Body)] != op.Body {
+					t.Errorf("operation %s missing from %v", op.ID, operations)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadManifestsDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeManifest(t, filepath.Join(dir, "web.json"), manifestOf(getProduct))
+	writeManifest(t, filepath.Join(dir, "ios.json"), manifestOf(getCategory))
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("not a manifest"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	operations, err := LoadManifests(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 2 {
+		t.Errorf("LoadManifests() = %d operations, want 2", len(operations))
+	}
+
+	if _, err := LoadManifests(filepath.Join(dir, "missing")); err == nil {
+		t.Error("LoadManifests() of a missing path succeeded")
+	}
+}
+
+func TestMutateOperationParameters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	writeManifest(t, path, manifestOf(getProduct))
+
+	persisted := func(hash string) map[string]any {
+		return map[string]any{"persistedQuery": map[string]any{"version": 1, "sha256Hash": hash}}
+	}
+	tests := []struct {
+		name      string
+		params    graphql.RawParams
+		wantQuery string
+		enforced  bool // whether the enforced safelist accepts it
+		allowed   bool // whether the unenforced safelist accepts it
+	}{
+		{"safelisted query", graphql.RawParams{Query: getProduct}, getProduct, true, true},
+		{"unknown query", graphql.RawParams{Query: getCategory}, getCategory, false, true},
+		{"safelisted hash", graphql.RawParams{Extensions: persisted(Hash(getProduct))}, getProduct, true, true},
+		{"upper case hash", graphql.RawParams{Extensions: persisted(strings.ToUpper(Hash(getProduct)))}, getProduct, true, true},
+		{"unknown hash", graphql.RawParams{Extensions: persisted(Hash(getCategory))}, "", false, true},
+		{"no query", graphql.RawParams{}, "", true, true},
+	}
+	for _, enforce := range []bool{true, false} {
+		safelist, err := New(path, enforce)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tt := range tests {
+			params := tt.params
+			err := safelist.MutateOperationParameters(context.Background(), &params)
+			want := tt.allowed
+			if enforce {
+				want = tt.enforced
+			}
+			if (err == nil) != want {
+				t.Errorf("%s (enforce %v): error = %v, want accepted %v", tt.name, enforce, err, want)
+			}
+			if err == nil && params.Query != tt.wantQuery {
+				t.Errorf("%s (enforce %v): query = %q, want %q", tt.name, enforce, params.Query, tt.wantQuery)
+			}
+			if err != nil && err.Extensions["code"] != errNotSafelisted {
+				t.Errorf("%s (enforce %v): code = %v, want %s", tt.name, enforce, err.Extensions["code"], errNotSafelisted)
+			}
+		}
+	}
+}
+
+func TestReloadKeepsOperationsOnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	writeManifest(t, path, manifestOf(getProduct))
+	safelist, err := New(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := safelist.Reload(); err == nil {
+		t.Fatal("Reload() of an invalid manifest succeeded")
+	}
+	if err := safelist.MutateOperationParameters(context.Background(), &graphql.RawParams{Query: getProduct}); err != nil {
+		t.Errorf("operation rejected after a failed reload: %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package tenant
+
+import (
+	"context"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// HeaderName is the HTTP header the gateway reads the tenant from.
+	HeaderName = "X-Tenant-ID"
+	// MetadataKey is the gRPC metadata key carrying the tenant between services.
+	MetadataKey = "x-tenant-id"
+	// PropertyKey is the Pulsar message property carrying the tenant of an event.
+	PropertyKey = "tenant"
+)
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the given tenant.
+func NewContext(ctx context.Context, tenantID string) context.Context {
+	if tenantID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, contextKey{}, tenantID)
+}
+
+// FromContext returns the tenant stored in ctx, if any.
+func FromContext(ctx context.Context) (string, bool) {
+	tenantID, ok := ctx.Value(contextKey{}).(string)
+	return tenantID, ok && tenantID != ""
+}
+
+// Middleware stores the tenant from the X-Tenant-ID header in the request context.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tenantID := r.Header.Get(HeaderName); tenantID != "" {
+			r = r.WithContext(NewContext(r.Context(), tenantID))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// UnaryClientInterceptor forwards the tenant in the context to the server as metadata.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if tenantID, ok := FromContext(ctx); ok {
+			ctx = metadata.AppendToOutgoingContext(ctx, MetadataKey, tenantID)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// UnaryServerInterceptor reads the tenant from the incoming metadata into the context.
+// Requests for a tenant that known reports as unknown are rejected with InvalidArgument.
+func UnaryServerInterceptor(known func(tenantID string) bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if values := md.Get(MetadataKey); len(values) > 0 && values[0] != "" {
+			if !known(values[0]) {
+				return nil, status.Errorf(codes.InvalidArgument, "unknown tenant %q", values[0])
+			}
+			ctx = NewContext(ctx, values[0])
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of UnaryServerInterceptor.
+func StreamServerInterceptor(known func(tenantID string) bool) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		md, _ := metadata.FromIncomingContext(ss.Context())
+		if values := md.Get(MetadataKey); len(values) > 0 && values[0] != "" {
+			if !known(values[0]) {
+				return status.Errorf(codes.InvalidArgument, "unknown tenant %q", values[0])
+			}
+			ss = &serverStream{ServerStream: ss, ctx: NewContext(ss.Context(), values[0])}
+		}
+		return handler(srv, ss)
+	}
+}
+
+// serverStream overrides the context of a grpc.ServerStream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,11 @@
+// Package migrations embeds the versioned schema migrations applied by internal/migrate.
+//
+// Files are named <version>_<name>.<ext>. CQL files are templates: {{.Keyspace}} is replaced
+// with the keyspace being migrated. Data migrations that need code, such as backfills, are
+// Go migrations registered in internal/migrate and share the version sequence of these files.
+package migrations
+
+import "embed"
+
+//go:embed cassandra/*.cql postgres/*.sql
+var FS embed.FS
@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS categories (
+    id bigint PRIMARY KEY,
+    name text NOT NULL,
+    description text NOT NULL,
+    created_at timestamptz NOT NULL
+);
+
+
+CREATE TABLE IF NOT EXISTS products (
+    id bigint NOT NULL,
+    category_id bigint NOT NULL,
+    name text NOT NULL,
+    description text NOT NULL,
+    price real NOT NULL,
+    stock integer NOT NULL,
+    created_at timestamptz NOT NULL,
+    updated_at timestamptz NOT NULL,
+    PRIMARY KEY (category_id, id)
+);
+
+
+CREATE TABLE IF NOT EXISTS products_outbox (
+    id uuid PRIMARY KEY,
+    payload text NOT NULL,
+    event_type text NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS products_outbox_created_at_idx ON products_outbox (created_at);
+
+CREATE TABLE IF NOT EXISTS inventory (
+    product_id bigint,
+    category_id bigint,
+    stock_count integer,
+    created_at timestamptz,
+    last_updated_at timestamptz,
+    PRIMARY KEY (product_id, category_id)
+);
@@ -0,0 +1,12 @@
+-- Exact prices: price_amount replaces the real price column, which is kept for rollbacks.
+-- Going through text keeps the shortest decimal form of each float, so 19.99 stays 19.99.
+ALTER TABLE products
+    ADD COLUMN price_amount numeric(28, 9),
+    ADD COLUMN currency char(3);
+
+UPDATE products SET price_amount = price::text::numeric, currency = 'USD' WHERE price_amount IS NULL;
+
+ALTER TABLE products
+    ALTER COLUMN price_amount SET NOT NULL,
+    ALTER COLUMN currency SET NOT NULL,
+    ALTER COLUMN price DROP NOT NULL;
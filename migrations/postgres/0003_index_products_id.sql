@@ -0,0 +1 @@
+CREATE UNIQUE INDEX IF NOT EXISTS products_id_idx ON products (id);
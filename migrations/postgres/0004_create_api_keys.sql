@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id text PRIMARY KEY,
+    secret_hash bytea NOT NULL,
+    name text NOT NULL,
+    requests_per_second double precision NOT NULL,
+    burst integer NOT NULL,
+    created_at timestamptz NOT NULL,
+    revoked_at timestamptz
+);
@@ -0,0 +1,2 @@
+-- The id of the request that caused each outbox event, relayed as a Pulsar message property.
+ALTER TABLE products_outbox ADD COLUMN request_id text;